@@ -2,30 +2,61 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/BountyM/effectiveMobileTestTask/internal/auth"
 	"github.com/BountyM/effectiveMobileTestTask/internal/config"
 	"github.com/BountyM/effectiveMobileTestTask/internal/handler"
 	"github.com/BountyM/effectiveMobileTestTask/internal/logger"
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/BountyM/effectiveMobileTestTask/internal/ratelimit"
 	"github.com/BountyM/effectiveMobileTestTask/internal/repository"
+	"github.com/BountyM/effectiveMobileTestTask/internal/repository/migrations"
 	server "github.com/BountyM/effectiveMobileTestTask/internal/server"
 	"github.com/BountyM/effectiveMobileTestTask/internal/service"
+	"github.com/BountyM/effectiveMobileTestTask/internal/tracing"
 	_ "github.com/lib/pq"
 
 	_ "github.com/BountyM/effectiveMobileTestTask/docs"
 )
 
+// Сведения о сборке задаются при компиляции:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.revision=$(git rev-parse HEAD)"
+//
+// Если они не заданы, используются данные, встроенные компилятором Go.
+var (
+	version   = ""
+	revision  = ""
+	buildTime = ""
+)
+
 // @title Subscription API
 // @version 1.0
 // @description API для управления подписками
+// @description Ошибки возвращаются в формате application/problem+json (RFC 7807). Клиенты, передающие Accept: application/json, получают прежний формат {"error": "...", "code": "..."}.
 // @host localhost:8080
 // @BasePath /
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <токен>" (HS256 или RS256)
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API-ключ для вызовов между сервисами
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -34,11 +65,44 @@ func main() {
 	}
 	log := logger.New(cfg.Logger)
 	log.Info("Logger initialized")
-	db, err := repository.NewPostgresDB(cfg.DB)
+	// Ожидание базы при запуске можно прервать сигналом завершения
+	connectCtx, stopConnect := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+
+	// Подкоманда migrate управляет схемой БД и завершает работу, не запуская сервер
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := withMigrator(connectCtx, cfg.DB, log, func(migrator *repository.Migrator) error {
+			return runMigrate(context.Background(), migrator, os.Args[2:], log)
+		})
+		stopConnect()
+		if err != nil {
+			log.Error("Migration command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.DB.AutoMigrate {
+		err := withMigrator(connectCtx, cfg.DB, log, func(migrator *repository.Migrator) error {
+			applied, err := migrator.Up(context.Background())
+			for _, m := range applied {
+				log.Info("Migration applied", "version", m.Version, "name", m.Name)
+			}
+			return err
+		})
+		if err != nil {
+			stopConnect()
+			log.Error("Failed to apply migrations", "error", err)
+			return
+		}
+	}
+
+	db, err := repository.NewPostgresDB(connectCtx, cfg.DB, log)
+	stopConnect()
 	if err != nil {
 		log.Error("Failed to initialize database", "error", err)
 		return
 	}
+
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			log.Error("Error occurred on DB connection close", "error", closeErr)
@@ -47,9 +111,72 @@ func main() {
 		}
 	}()
 
-	repo := repository.New(db)
-	services := service.New(repo)
-	handlers := handler.New(services, log) // переименовано для избежания конфликта с пакетом
+	build := buildInfo()
+	tracerProvider, err := tracing.New(context.Background(), cfg.Trace, build)
+	if err != nil {
+		log.Error("Failed to initialize tracing", "error", err)
+		return
+	}
+	defer func() {
+		// Отправляем накопленные спаны перед выходом
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			log.Error("Error occurred on tracer provider shutdown", "error", err)
+		}
+	}()
+
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = auth.New(context.Background(), cfg.Auth)
+		if err != nil {
+			log.Error("Failed to initialize authentication", "error", err)
+			return
+		}
+	} else {
+		log.Warn("Authentication is disabled, API is publicly accessible")
+	}
+
+	// Периодически удаляем просроченные ключи идемпотентности и корзины лимитов
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store
+		switch cfg.RateLimit.Store {
+		case "memory":
+			store = ratelimit.NewMemoryStore()
+		case "postgres":
+			rateLimitStore := repository.NewRateLimitPostgres(db, cfg.DB.StatementTimeout)
+			go purgeRateLimitBuckets(purgeCtx, rateLimitStore, log)
+			store = rateLimitStore
+		default:
+			log.Error("Unknown rate limit store, expected memory or postgres", "store", cfg.RateLimit.Store)
+			return
+		}
+		limiter, err = ratelimit.New(cfg.RateLimit, store)
+		if err != nil {
+			log.Error("Failed to initialize rate limiting", "error", err)
+			return
+		}
+	}
+
+	appMetrics := metrics.New(log)
+	appMetrics.RegisterDB(db.DB, cfg.DB.Dbname)
+
+	repo := repository.New(db, cfg.DB)
+	services := service.New(repo, appMetrics, build)
+	appMetrics.RegisterSubscriptionStats(services.Subscription.Stats)
+	handlers := handler.New(services, log, cfg.HTTP, appMetrics, authenticator, limiter, cfg.Trace.ServiceName) // переименовано для избежания конфликта с пакетом
+
+	router, err := handlers.InitRoutes()
+	if err != nil {
+		log.Error("Failed to initialize routes", "error", err)
+		return
+	}
+
+	go purgeIdempotencyKeys(purgeCtx, services.Idempotency, log)
 
 	srv := &server.Server{}
 
@@ -60,7 +187,7 @@ func main() {
 		defer close(serverErr)
 		// Формируем правильный адрес с двоеточием
 		addr := ":" + cfg.Port
-		if err := srv.Run(addr, handlers.InitRoutes()); err != nil && err != http.ErrServerClosed {
+		if err := srv.Run(addr, router); err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
 	}()
@@ -79,6 +206,11 @@ func main() {
 		return
 	}
 
+	// Сначала снимаем экземпляр с балансировки и даём ей время заметить это
+	handlers.StartDraining()
+	log.Info("Draining traffic before shutdown", "delay", cfg.HTTP.ShutdownDrainDelay)
+	time.Sleep(cfg.HTTP.ShutdownDrainDelay)
+
 	// Graceful shutdown сервера
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -88,3 +220,157 @@ func main() {
 		log.Info("Server stopped gracefully")
 	}
 }
+
+// buildInfo собирает сведения о сборке из флагов компоновщика и данных runtime/debug
+func buildInfo() models.BuildInfo {
+	info := models.BuildInfo{
+		Version:   version,
+		Revision:  revision,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "" {
+		info.Version = bi.Main.Version
+	}
+	for _, setting := range bi.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Revision == "":
+			info.Revision = setting.Value
+		case setting.Key == "vcs.time" && info.BuildTime == "":
+			info.BuildTime = setting.Value
+		}
+	}
+	return info
+}
+
+// idempotencyPurgeInterval — период удаления просроченных ключей идемпотентности
+const idempotencyPurgeInterval = 10 * time.Minute
+
+// purgeIdempotencyKeys удаляет просроченные ключи идемпотентности до отмены ctx
+func purgeIdempotencyKeys(ctx context.Context, idempotency service.Idempotency, log *slog.Logger) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := idempotency.PurgeExpired(ctx)
+			if err != nil {
+				log.Error("Failed to purge expired idempotency keys", "error", err)
+				continue
+			}
+			log.Debug("Expired idempotency keys purged", "deleted", deleted)
+		}
+	}
+}
+
+// rateLimitPurgeInterval — период удаления наполнившихся корзин лимитов
+const rateLimitPurgeInterval = 10 * time.Minute
+
+// purgeRateLimitBuckets удаляет наполнившиеся корзины лимитов до отмены ctx
+func purgeRateLimitBuckets(ctx context.Context, store *repository.RateLimitPostgres, log *slog.Logger) {
+	ticker := time.NewTicker(rateLimitPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := store.DeleteExpired(ctx)
+			if err != nil {
+				log.Error("Failed to purge rate limit buckets", "error", err)
+				continue
+			}
+			log.Debug("Rate limit buckets purged", "deleted", deleted)
+		}
+	}
+}
+
+// withMigrator подключается к базе от имени владельца схемы (cfg.Migration)
+// и вызывает fn с мигратором встроенных миграций
+func withMigrator(ctx context.Context, cfg config.DB, log *slog.Logger, fn func(*repository.Migrator) error) error {
+	db, err := repository.NewPostgresDB(ctx, cfg.Migration(), log)
+	if err != nil {
+		return fmt.Errorf("failed to connect for migrations: %w", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Error("Error occurred on DB connection close", "error", closeErr)
+		}
+	}()
+
+	migrator, err := repository.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return fn(migrator)
+}
+
+// runMigrate выполняет подкоманду migrate:
+//
+//	migrate up            — применить все неприменённые миграции
+//	migrate down          — откатить последнюю применённую миграцию
+//	migrate status        — показать состояние миграций
+//	migrate goto VERSION  — привести схему к версии VERSION (0 — откатить всё)
+func runMigrate(ctx context.Context, migrator *repository.Migrator, args []string, log *slog.Logger) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down|status|goto VERSION")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			log.Info("Migration applied", "version", m.Version, "name", m.Name)
+		}
+		log.Info("Migrations are up to date", "applied", len(applied))
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			log.Info("No applied migrations to revert")
+			return nil
+		}
+		log.Info("Migration reverted", "version", reverted.Version, "name", reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.AppliedAt != nil {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\t%s\t%s\n", st.Version, st.Name, state)
+		}
+	case "goto":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate goto VERSION")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[1], err)
+		}
+		if err := migrator.Goto(ctx, version); err != nil {
+			return err
+		}
+		log.Info("Schema migrated to version", "version", version)
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down, status or goto", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+#!/bin/sh
+# Создаёт роль, которой подключается сервис. POSTGRES_USER владеет схемой и применяет
+# миграции; роль сервиса получает права на данные через группу subscription_app
+# (миграция 20260425090000) и подчиняется политикам RLS.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v app_user="$APP_DB_USERNAME" -v app_password="$APP_DB_PASSWORD" <<'EOSQL'
+CREATE ROLE subscription_app NOLOGIN NOSUPERUSER NOBYPASSRLS;
+CREATE ROLE :"app_user" LOGIN PASSWORD :'app_password' NOSUPERUSER NOBYPASSRLS IN ROLE subscription_app;
+EOSQL
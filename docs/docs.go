@@ -15,9 +15,63 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс работает. Зависимости не проверяются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка живости",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.healthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет соединение с базой за ограниченное время и возвращает версию схемы и сведения о сборке.\nВо время остановки сервиса отвечает 503 со статусом draining.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.readinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "База недоступна или сервис останавливается",
+                        "schema": {
+                            "$ref": "#/definitions/handler.readinessResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/subscription": {
             "post": {
-                "description": "Создаёт новую подписку для пользователя",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создаёт новую подписку для пользователя. Пробный период задаётся trial_end или trial_months, промо-цены — promotions; цена price действует после них. С заголовком Idempotency-Key повторный запрос с тем же ключом и телом возвращает исходный ответ (с заголовком Idempotent-Replayed: true) вместо создания дубликата; тот же ключ с другим телом отклоняется с 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,6 +83,12 @@ const docTemplate = `{
                 ],
                 "summary": "Создать подписку",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности запроса",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "Данные подписки",
                         "name": "request",
@@ -37,6 +97,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.reqCreate"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -57,31 +123,65 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректные данные: invalid input body",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Запрос с этим Idempotency-Key ещё выполняется, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку или Idempotency-Key использован с другим телом",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     }
                 }
             }
         },
-        "/subscription/cost": {
+        "/subscription/cancellations/reasons": {
             "post": {
-                "description": "Рассчитывает общую стоимость подписок пользователя за указанный период",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает число действующих (не отозванных) отмен подписок по причинам, последний оплачиваемый месяц которых попадает в период start_date..end_date включительно, и lost_monthly_spend — цену отменённых подписок в пересчёте на месяц. Фильтры совпадают с расчётом стоимости. Причины упорядочены по убыванию числа отмен.",
                 "consumes": [
                     "application/json"
                 ],
@@ -91,26 +191,35 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Рассчитать стоимость подписок",
+                "summary": "Отчёт по причинам отмены",
                 "parameters": [
                     {
-                        "description": "Параметры расчёта стоимости",
+                        "description": "Период и фильтры отчёта",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "$ref": "#/definitions/handler.reqCost"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешный расчёт, возвращает стоимость",
+                        "description": "Отмены по причинам",
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "cost": {
-                                    "type": "number"
+                                "reasons": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/models.CancellationReasonStats"
+                                    }
                                 },
                                 "res": {
                                     "type": "string"
@@ -121,31 +230,65 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректные данные: invalid input body",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     }
                 }
             }
         },
-        "/subscription/{id}": {
-            "put": {
-                "description": "Обновляет данные подписки по ID. Принимает JSON с данными подписки.",
+        "/subscription/cost": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Рассчитывает общую стоимость подписок за указанный период (start_date и end_date включительно) как сумму списаний, даты которых попадают в период. Даты списаний определяются интервалом оплаты подписки: ежемесячная подписка списывается каждый месяц, годовая — раз в год, в день billing_anchor_day. Подписки без даты окончания учитываются до конца периода. В пробный период (до trial_end включительно) списаний нет, расписание списаний начинается с месяца после него; в промо-периоды списывается промо-цена. Списания в месяцы приостановки подписки не учитываются.",
                 "consumes": [
                     "application/json"
                 ],
@@ -155,31 +298,33 @@ const docTemplate = `{
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Обновить подписку",
+                "summary": "Рассчитать стоимость подписок",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID подписки",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Обновляемые данные подписки",
+                        "description": "Параметры расчёта стоимости",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.reqCreate"
+                            "$ref": "#/definitions/handler.reqCost"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное обновление",
+                        "description": "Успешный расчёт, возвращает стоимость",
                         "schema": {
                             "type": "object",
                             "properties": {
+                                "cost": {
+                                    "type": "number"
+                                },
                                 "res": {
                                     "type": "string"
                                 }
@@ -189,51 +334,104 @@ const docTemplate = `{
                     "400": {
                         "description": "Некорректные данные: invalid input body",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет подписку по её ID",
+            }
+        },
+        "/subscription/cost/breakdown": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает сумму списаний по подпискам за каждый календарный месяц периода (start_date и end_date включительно) и число подписок, списанных в месяце; месяцы без списаний не возвращаются. Фильтры совпадают с расчётом стоимости. При указании group_by месяцы дополнительно разбиваются по service_name и/или user_id.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Удалить подписку",
+                "summary": "Помесячная разбивка стоимости подписок",
                 "parameters": [
+                    {
+                        "description": "Параметры разбивки стоимости",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqCostBreakdown"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "ID подписки",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешное удаление",
+                        "description": "Помесячная разбивка стоимости",
                         "schema": {
                             "type": "object",
                             "properties": {
+                                "buckets": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/models.CostBucket"
+                                    }
+                                },
                                 "res": {
                                     "type": "string"
                                 }
@@ -241,94 +439,1662 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID подписки: invalid input body",
+                        "description": "Некорректные данные: invalid input body",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     }
                 }
             }
         },
-        "/subscription/{user_id}": {
+        "/subscription/{id}": {
             "get": {
-                "description": "Возвращает список подписок пользователя с пагинацией. Если page или limit не указаны, используются значения по умолчанию: page=1, limit=10.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает подписку по её ID. До появления этого маршрута путь /subscription/{user_id} возвращал подписки пользователя; теперь они доступны по GET /users/{user_id}/subscriptions. Ответ содержит заголовок ETag из версии подписки и хеша её представления: значения на текущую дату (state, in_trial, price, monthly_price) меняют ETag и без изменения подписки. При совпадении If-None-Match возвращается 304; для If-Match значима только версия.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "subscriptions"
                 ],
-                "summary": "Получить подписки пользователя",
+                "summary": "Получить подписку",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID пользователя",
-                        "name": "user_id",
+                        "description": "ID подписки",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из предыдущего ответа",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Список подписок с пагинацией",
+                        "description": "Подписка",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "res": {
                                     "type": "string"
                                 },
-                                "subscriptions": {
-                                    "type": "array",
-                                    "items": {
-                                        "$ref": "#/definitions/models.Subscription"
-                                    }
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Подписка не изменилась"
+                    },
+                    "400": {
+                        "description": "Некорректный ID подписки",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Обновляет данные подписки по ID. Принимает JSON с данными подписки. При переданном If-Match подписка обновляется, только если её версия совпадает с ETag; новый ETag возвращается в ответе.\nНовая цена действует с текущего месяца (или с начала подписки, если она ещё не началась) и не меняет стоимость прошлых месяцев; изменить цену с другого месяца можно через POST /subscription/{id}/prices.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Обновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки; обязателен в строгом режиме",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Обновляемые данные подписки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqCreate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное обновление",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные: invalid input body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Изменение end_date отменённой подписки",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Удаляет подписку по её ID. При переданном If-Match подписка удаляется, только если её версия совпадает с ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Удалить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки; обязателен в строгом режиме",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешное удаление",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID подписки: invalid input body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Изменяет только переданные поля подписки. Тело — JSON Merge Patch (RFC 7396, Content-Type: application/merge-patch+json или application/json) либо JSON Patch (RFC 6902, Content-Type: application/json-patch+json).\nПатч применяется к представлению подписки в формате запроса на создание (даты MM-YYYY); \"end_date\": null в merge patch сбрасывает дату окончания, отсутствие поля оставляет её без изменений. Результат проверяется так же, как при создании, включая порядок дат. Новая цена действует с текущего месяца, как при PUT. Пробный период в документе задан полем trial_end; чтобы задать его длительностью, патч должен одновременно сбросить trial_end и передать trial_months. Массив promotions заменяется целиком.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Частично обновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки; обязателен в строгом режиме",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Изменяемые поля подписки или массив операций JSON Patch",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqCreate"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после изменения",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или документ патча",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка изменена параллельно (запрос без If-Match) или изменяется end_date отменённой подписки",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый тип содержимого",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Результат патча не прошёл проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отменяет подписку с указанием причины: последний оплачиваемый месяц записывается в end_date. При effective=immediately это текущий месяц, при effective=end_of_period (по умолчанию) — месяц перед следующим списанием, для ежемесячной подписки — тоже текущий; effective_month задаёт его явно. Месяц не может быть в прошлом, раньше начала или позже прежней даты окончания подписки.\nПодписку нельзя отменить повторно или после окончания (409). Ещё не начавшуюся подписку можно только удалить или отменить с effective_month, иначе возвращается 409. Отмену можно отозвать через POST /subscription/{id}/uncancel, пока не прошёл последний оплачиваемый месяц.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Отменить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Причина и момент отмены",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqCancel"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после отмены",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка уже отменена, закончилась или ещё не началась",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Приостанавливает подписку на месяцы start_month..end_month включительно: эти месяцы не учитываются в стоимости и расходах, а списания за периоды длиннее месяца уменьшаются пропорционально приостановленным месяцам. Без start_month приостановка начинается с текущего месяца, без end_month длится до возобновления. Тело запроса можно не передавать.\nПриостановка не может начинаться раньше начала или позже окончания подписки и пересекаться с уже записанными приостановками (409).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Приостановить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Месяцы приостановки",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqPause"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после приостановки",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка уже приостановлена в этом периоде",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/pause/{start_month}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Удаляет приостановку подписки, которая начинается с месяца start_month (MM-YYYY) и ещё не началась. Начавшуюся приостановку завершает POST /subscription/{id}/resume (409).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Отменить запланированную приостановку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Первый месяц приостановки (MM-YYYY)",
+                        "name": "start_month",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после отмены приостановки",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка или приостановка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Приостановка уже началась",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Некорректный месяц",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/prices": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Записывает цену, действующую с месяца effective_from до следующего изменения. Месяц в прошлом исправляет историю цен и стоимость прошлых месяцев, месяц в будущем планирует изменение. Повторный запрос с тем же месяцем заменяет цену этого месяца.\nМесяц не может быть раньше начала подписки. Ответ содержит подписку с действующей ценой и историей цен.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Изменить цену подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Цена и месяц, с которого она действует",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqPriceChange"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после изменения цены",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возобновляет подписку с месяца month (по умолчанию — с текущего): приостановка, в которую входит этот месяц, заканчивается предыдущим месяцем, а начинающаяся с него отменяется. Тело запроса можно не передавать.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Возобновить подписку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Месяц возобновления",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.reqResume"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после возобновления",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или тело запроса",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка не приостановлена в этом месяце",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Данные не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{id}/uncancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает действующую отмену подписки и восстанавливает прежнюю дату окончания. Доступно, пока не прошёл последний оплачиваемый месяц отмены; иначе, как и для неотменённой подписки, возвращается 409. Тело запроса не требуется.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Отозвать отмену подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID подписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag подписки",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка после отзыва отмены",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "res": {
+                                    "type": "string"
+                                },
+                                "subscription": {
+                                    "$ref": "#/definitions/models.Subscription"
                                 }
                             }
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID пользователя: invalid input body",
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Подписка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка не отменена или отмена уже вступила в силу",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия подписки не совпадает с If-Match",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "428": {
+                        "description": "Не передан If-Match в строгом режиме",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscription/{user_id}/{page}/{limit}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Устаревший маршрут, оставлен для совместимости: то же, что GET /users/{user_id}/subscriptions?page={page}\u0026limit={limit}. Ответ содержит заголовки Deprecation и Link на новый маршрут.\nПрежний маршрут GET /subscription/{user_id} без page и limit не сохранён: этот путь теперь возвращает подписку по её ID, для списка подписок пользователя используйте GET /users/{user_id}/subscriptions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Получить подписки пользователя (устаревший маршрут)",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы",
+                        "name": "page",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество записей на страницу",
+                        "name": "limit",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список подписок с пагинацией",
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "error": {
+                                "res": {
                                     "type": "string"
+                                },
+                                "subscriptions": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/models.Subscription"
+                                    }
                                 }
                             }
                         }
                     },
+                    "400": {
+                        "description": "Некорректный ID пользователя: invalid input body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает подписки всех пользователей с фильтрацией, сортировкой и пагинацией. Если page или limit не указаны, используются значения по умолчанию: page=1, limit=10.\nВ режиме offset ответ содержит total — общее число записей, подходящих под фильтры. В режиме cursor (pagination=cursor или непустой cursor) страницы читаются по ключу сортировки: ответ содержит next_cursor, который передаётся в cursor для получения следующей страницы; пустой next_cursor означает конец списка.\nin_trial отбирает начавшиеся подписки, пробный период которых идёт в текущем месяце (или все остальные при in_trial=false); trial_ending_within_days — подписки, первое платное списание которых наступит не позже чем через указанное число дней. state отбирает подписки по состоянию в текущем месяце.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Список подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "подписка активна в этом месяце",
+                        "name": "active_on",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Непрозрачный курсор next_cursor из предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "has_end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "подписка началась и пробный период идёт в текущем месяце",
+                        "name": "in_trial",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Режим пагинации: offset (по умолчанию) или cursor.\nНепустой cursor включает режим cursor автоматически",
+                        "name": "pagination",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "price_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "price_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "точное совпадение",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "совпадение по префиксу",
+                        "name": "service_name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключи через запятую с необязательным направлением: price:desc,start_date:asc.\nДопустимые ключи: service_name, price, user_id, start_date, end_date",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "scheduled",
+                            "active",
+                            "paused",
+                            "ended"
+                        ],
+                        "type": "string",
+                        "description": "Состояние подписки в текущем месяце",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Первое платное списание после пробного периода — не позже чем через столько дней",
+                        "name": "trial_ending_within_days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из предыдущего ответа",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница подписок",
                         "schema": {
                             "type": "object",
                             "properties": {
-                                "error": {
+                                "limit": {
+                                    "type": "integer"
+                                },
+                                "next_cursor": {
+                                    "type": "string"
+                                },
+                                "page": {
+                                    "type": "integer"
+                                },
+                                "res": {
                                     "type": "string"
+                                },
+                                "subscriptions": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/models.Subscription"
+                                    }
+                                },
+                                "total": {
+                                    "type": "integer"
                                 }
                             }
                         }
+                    },
+                    "304": {
+                        "description": "Страница не изменилась"
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "422": {
+                        "description": "Параметры не прошли проверку",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: internal error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
                     }
                 }
             }
         },
-        "/subscription/{user_id}/{page}/{limit}": {
+        "/users/{user_id}/subscriptions": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "description": "Возвращает список подписок пользователя с пагинацией. Если page или limit не указаны, используются значения по умолчанию: page=1, limit=10.",
                 "produces": [
                     "application/json"
@@ -349,13 +2115,25 @@ const docTemplate = `{
                         "type": "integer",
                         "description": "Номер страницы",
                         "name": "page",
-                        "in": "path"
+                        "in": "query"
                     },
                     {
                         "type": "integer",
                         "description": "Количество записей на страницу",
                         "name": "limit",
-                        "in": "path"
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из предыдущего ответа",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -376,26 +2154,49 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "304": {
+                        "description": "Список не изменился"
+                    },
                     "400": {
                         "description": "Некорректный ID пользователя: invalid input body",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "401": {
+                        "description": "Не переданы или неверны учётные данные",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "403": {
+                        "description": "Операция недоступна вызывающему",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "Запрошены подписки другого пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов, см. Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера: internal error",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "error": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/handler.problemDetails"
+                        }
+                    },
+                    "503": {
+                        "description": "База данных недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/handler.problemDetails"
                         }
                     }
                 }
@@ -403,6 +2204,95 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "handler.healthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.problemDetails": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.readinessResponse": {
+            "type": "object",
+            "properties": {
+                "build": {
+                    "$ref": "#/definitions/models.BuildInfo"
+                },
+                "database": {
+                    "description": "Database — результат проверки базы: ok или unavailable. Подробности ошибки\nтолько в журнале: маршрут открыт без аутентификации",
+                    "type": "string"
+                },
+                "schema_version": {
+                    "description": "SchemaVersion — версия последней применённой миграции (0 — миграции не применялись)",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.reqCancel": {
+            "type": "object",
+            "properties": {
+                "effective": {
+                    "type": "string",
+                    "enum": [
+                        "immediately",
+                        "end_of_period"
+                    ]
+                },
+                "effective_month": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "too_expensive",
+                        "not_using",
+                        "switched_service",
+                        "missing_features",
+                        "technical_issues",
+                        "other"
+                    ]
+                }
+            }
+        },
         "handler.reqCost": {
             "type": "object",
             "properties": {
@@ -420,49 +2310,361 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.reqCostBreakdown": {
+            "type": "object",
+            "properties": {
+                "end_date": {
+                    "type": "string"
+                },
+                "group_by": {
+                    "description": "Допустимые значения: service_name, user_id",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.reqCreate": {
             "type": "object",
             "properties": {
+                "billing_anchor_day": {
+                    "type": "integer",
+                    "maximum": 31,
+                    "minimum": 1
+                },
+                "billing_interval": {
+                    "description": "Цена списывается каждые billing_interval_count единиц billing_interval\n(по умолчанию — ежемесячно), первое списание — в день billing_anchor_day\nмесяца начала (по умолчанию — первого числа)",
+                    "type": "string",
+                    "enum": [
+                        "day",
+                        "week",
+                        "month",
+                        "year"
+                    ]
+                },
+                "billing_interval_count": {
+                    "type": "integer",
+                    "minimum": 1
+                },
                 "end_date": {
                     "type": "string"
                 },
                 "price": {
                     "type": "integer"
                 },
+                "promotions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.reqPromotion"
+                    }
+                },
                 "service_name": {
                     "type": "string"
                 },
                 "start_date": {
                     "type": "string"
                 },
+                "trial_end": {
+                    "description": "Пробный период задаётся последним бесплатным месяцем trial_end (MM-YYYY)\nили длительностью trial_months от месяца начала, но не обоими сразу",
+                    "type": "string"
+                },
+                "trial_months": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.reqPause": {
+            "type": "object",
+            "properties": {
+                "end_month": {
+                    "type": "string"
+                },
+                "start_month": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.reqPriceChange": {
+            "type": "object",
+            "properties": {
+                "effective_from": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.reqPromotion": {
+            "type": "object",
+            "properties": {
+                "end_month": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "start_month": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.reqResume": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BuildInfo": {
+            "type": "object",
+            "properties": {
+                "build_time": {
+                    "type": "string"
+                },
+                "go_version": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Cancellation": {
+            "type": "object",
+            "properties": {
+                "cancelled_at": {
+                    "type": "string"
+                },
+                "effective_month": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "too_expensive",
+                        "not_using",
+                        "switched_service",
+                        "missing_features",
+                        "technical_issues",
+                        "other"
+                    ]
+                }
+            }
+        },
+        "models.CancellationReasonStats": {
+            "type": "object",
+            "properties": {
+                "cancellations": {
+                    "type": "integer"
+                },
+                "lost_monthly_spend": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CostBucket": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Pause": {
+            "type": "object",
+            "properties": {
+                "end_month": {
+                    "type": "string"
+                },
+                "start_month": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PriceChange": {
+            "type": "object",
+            "properties": {
+                "effective_from": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Promotion": {
+            "type": "object",
+            "properties": {
+                "end_month": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "start_month": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Subscription": {
             "type": "object",
             "properties": {
+                "billing_anchor_day": {
+                    "type": "integer"
+                },
+                "billing_interval": {
+                    "description": "Цена списывается каждые billing_interval_count единиц billing_interval;\nпервое списание — в день billing_anchor_day месяца начала подписки.",
+                    "type": "string",
+                    "enum": [
+                        "day",
+                        "week",
+                        "month",
+                        "year"
+                    ]
+                },
+                "billing_interval_count": {
+                    "type": "integer"
+                },
+                "cancellation": {
+                    "description": "Cancellation — действующая отмена подписки; возвращается только при чтении одной подписки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Cancellation"
+                        }
+                    ]
+                },
                 "end_date": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "in_trial": {
+                    "type": "boolean"
+                },
+                "monthly_price": {
+                    "description": "MonthlyPrice — цена в пересчёте на месяц, см. MonthlyEquivalent",
+                    "type": "integer"
+                },
+                "pauses": {
+                    "description": "Pauses — приостановки подписки; возвращаются только при чтении одной подписки",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Pause"
+                    }
+                },
                 "price": {
                     "type": "integer"
                 },
+                "price_history": {
+                    "description": "PriceHistory — цены подписки по месяцам, с которых они действуют; Price — цена,\nдействующая сейчас. История возвращается только при чтении одной подписки.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PriceChange"
+                    }
+                },
+                "promotions": {
+                    "description": "Promotions — промо-периоды подписки; возвращаются только при чтении одной подписки",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Promotion"
+                    }
+                },
                 "service_name": {
                     "type": "string"
                 },
                 "start_date": {
                     "type": "string"
                 },
+                "state": {
+                    "description": "State — состояние подписки в текущем месяце, см. StateActive и др.",
+                    "type": "string",
+                    "enum": [
+                        "scheduled",
+                        "active",
+                        "paused",
+                        "ended"
+                    ]
+                },
+                "trial_end": {
+                    "description": "TrialEnd — последний месяц бесплатного пробного периода; списания начинаются\nсо следующего месяца. InTrial — подписка началась и пробный период идёт сейчас.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
                 "user_id": {
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version увеличивается при каждом изменении записи и отдаётся клиентам как ETag",
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API-ключ для вызовов между сервисами",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT в формате \"Bearer \u003cтокен\u003e\" (HS256 или RS256)",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -473,7 +2675,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{"http"},
 	Title:            "Subscription API",
-	Description:      "API для управления подписками",
+	Description:      "API для управления подписками\nОшибки возвращаются в формате application/problem+json (RFC 7807). Клиенты, передающие Accept: application/json, получают прежний формат {\"error\": \"...\", \"code\": \"...\"}.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
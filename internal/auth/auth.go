@@ -0,0 +1,145 @@
+// Package auth проверяет учётные данные вызывающего: JWT (HS256, RS256) и API-ключи
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnauthenticated возвращается, если учётные данные отсутствуют или не прошли проверку
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// claims — поля JWT, которые использует сервис
+type claims struct {
+	jwt.RegisteredClaims
+	Roles    []string `json:"roles"`
+	TenantID string   `json:"tenant_id"`
+}
+
+// apiKey — API-ключ из конфигурации; хранится только SHA-256 от ключа
+type apiKey struct {
+	name   string
+	hash   []byte
+	roles  []string
+	tenant string
+}
+
+// Authenticator проверяет токены и API-ключи
+type Authenticator struct {
+	secret  []byte
+	keys    *keySet
+	apiKeys []apiKey
+	parser  *jwt.Parser
+}
+
+// New создаёт Authenticator по конфигурации. Если ключи RS256 заданы,
+// они загружаются сразу, чтобы ошибка конфигурации обнаружилась при запуске.
+func New(ctx context.Context, cfg config.Auth) (*Authenticator, error) {
+	a := &Authenticator{}
+
+	var methods []string
+	if cfg.JWTSecret != "" {
+		a.secret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" || cfg.JWKSURL != "" {
+		keys, err := newKeySet(ctx, cfg.JWKSFile, cfg.JWKSURL, cfg.JWKSRefresh)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	for _, entry := range cfg.APIKeys {
+		key, err := parseAPIKey(entry)
+		if err != nil {
+			return nil, err
+		}
+		a.apiKeys = append(a.apiKeys, key)
+	}
+
+	if len(methods) == 0 && len(a.apiKeys) == 0 {
+		return nil, fmt.Errorf("auth: no JWT keys or API keys configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+// parseAPIKey разбирает запись вида имя:sha256-хеш[:роль,роль[:арендатор]]
+func parseAPIKey(entry string) (apiKey, error) {
+	parts := strings.Split(strings.TrimSpace(entry), ":")
+	if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+		return apiKey{}, fmt.Errorf("auth: invalid API key entry, expected name:sha256[:roles[:tenant]]")
+	}
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil || len(hash) != sha256.Size {
+		return apiKey{}, fmt.Errorf("auth: API key %q must be a hex-encoded SHA-256 hash", parts[0])
+	}
+	key := apiKey{name: parts[0], hash: hash}
+	if len(parts) >= 3 && parts[2] != "" {
+		key.roles = strings.Split(parts[2], ",")
+	}
+	if len(parts) == 4 {
+		if !models.ValidTenantID(parts[3]) {
+			return apiKey{}, fmt.Errorf("auth: API key %q has invalid tenant %q", parts[0], parts[3])
+		}
+		key.tenant = parts[3]
+	}
+	return key, nil
+}
+
+// AuthenticateToken проверяет подпись и claims JWT и возвращает принципала
+func (a *Authenticator) AuthenticateToken(ctx context.Context, token string) (models.Principal, error) {
+	var c claims
+	_, err := a.parser.ParseWithClaims(token, &c, func(t *jwt.Token) (any, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.secret, nil
+		case jwt.SigningMethodRS256.Alg():
+			kid, _ := t.Header["kid"].(string)
+			return a.keys.key(ctx, kid)
+		}
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	})
+	if err != nil {
+		return models.Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	if c.Subject == "" {
+		return models.Principal{}, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	return models.Principal{Subject: c.Subject, Roles: c.Roles, Method: models.AuthMethodJWT, TenantID: c.TenantID}, nil
+}
+
+// AuthenticateAPIKey ищет ключ среди настроенных. Хеши сравниваются за постоянное время.
+func (a *Authenticator) AuthenticateAPIKey(key string) (models.Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash) == 1 {
+			return models.Principal{Subject: k.name, Roles: k.roles, Method: models.AuthMethodAPIKey, TenantID: k.tenant}, nil
+		}
+	}
+	return models.Principal{}, fmt.Errorf("%w: unknown API key", ErrUnauthenticated)
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// sign подписывает claims методом method ключом key и задаёт kid, если он не пустой
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, c jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() unexpected error: %v", err)
+	}
+	return signed
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a, err := New(context.Background(), config.Auth{
+		JWTSecret: testSecret,
+		Issuer:    "https://issuer.example",
+		Audience:  "subscription-api",
+		Leeway:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	valid := func() claims {
+		return claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+				Issuer:    "https://issuer.example",
+				Audience:  jwt.ClaimStrings{"subscription-api"},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			Roles:    []string{models.RoleAdmin},
+			TenantID: "acme",
+		}
+	}
+	with := func(change func(c *claims)) claims {
+		c := valid()
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		want    models.Principal
+		wantErr bool
+	}{
+		{
+			name:  "valid HS256 token",
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", valid()),
+			want: models.Principal{
+				Subject:  "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+				Roles:    []string{models.RoleAdmin},
+				Method:   models.AuthMethodJWT,
+				TenantID: "acme",
+			},
+		},
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), "", valid()), wantErr: true},
+		{name: "disallowed HS384", token: sign(t, jwt.SigningMethodHS384, []byte(testSecret), "", valid()), wantErr: true},
+		{name: "unsigned token", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", valid()), wantErr: true},
+		{name: "no subject", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.Subject = "" })), wantErr: true},
+		{
+			name:    "expired",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) })),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.ExpiresAt = nil })),
+			wantErr: true,
+		},
+		{
+			name:    "not valid yet",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.Audience = jwt.ClaimStrings{"billing"} })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", with(func(c *claims) { c.Issuer = "https://evil.example" })),
+			wantErr: true,
+		},
+		{name: "malformed", token: "not.a.token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.AuthenticateToken(context.Background(), tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("AuthenticateToken() error = %v, want ErrUnauthenticated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateToken() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthenticateToken() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTokenJWKS(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	unknown, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	toJWK := func(kid string, key *rsa.PrivateKey) jwk {
+		return jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	data, err := json.Marshal(map[string][]jwk{"keys": {toJWK("k1", key1), toJWK("k2", key2)}})
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	a, err := New(context.Background(), config.Auth{JWKSFile: file, JWKSRefresh: time.Hour})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	c := claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "svc",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "first key", token: sign(t, jwt.SigningMethodRS256, key1, "k1", c)},
+		{name: "second key", token: sign(t, jwt.SigningMethodRS256, key2, "k2", c)},
+		{name: "key of another kid", token: sign(t, jwt.SigningMethodRS256, key1, "k2", c), wantErr: true},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, unknown, "k3", c), wantErr: true},
+		// Без kid ключ выбирается, только если в наборе он один
+		{name: "no kid with several keys", token: sign(t, jwt.SigningMethodRS256, key1, "", c), wantErr: true},
+		// Секрет HS256 не настроен, поэтому HS256 не входит в список допустимых алгоритмов
+		{name: "HS256 without a secret", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", c), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.AuthenticateToken(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AuthenticateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAPIKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("key"))
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		entry   string
+		want    apiKey
+		wantErr bool
+	}{
+		{name: "name and hash", entry: "billing:" + hash, want: apiKey{name: "billing", hash: sum[:]}},
+		{
+			name:  "roles and tenant",
+			entry: " billing:" + hash + ":admin,reader:acme ",
+			want:  apiKey{name: "billing", hash: sum[:], roles: []string{"admin", "reader"}, tenant: "acme"},
+		},
+		{name: "tenant without roles", entry: "billing:" + hash + "::acme", want: apiKey{name: "billing", hash: sum[:], tenant: "acme"}},
+		{name: "no hash", entry: "billing", wantErr: true},
+		{name: "empty name", entry: ":" + hash, wantErr: true},
+		{name: "plain key instead of hash", entry: "billing:secret", wantErr: true},
+		{name: "short hash", entry: "billing:" + hash[:32], wantErr: true},
+		{name: "invalid tenant", entry: "billing:" + hash + ":admin:acme corp", wantErr: true},
+		{name: "too many parts", entry: "billing:" + hash + ":admin:acme:extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAPIKey(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAPIKey(%q) error = %v, wantErr %v", tt.entry, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAPIKey(%q) = %+v, want %+v", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	sum := sha256.Sum256([]byte("s3cr3t"))
+	a, err := New(context.Background(), config.Auth{
+		APIKeys: []string{"billing:" + hex.EncodeToString(sum[:]) + ":admin:acme"},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		want    models.Principal
+		wantErr bool
+	}{
+		{
+			name: "known key",
+			key:  "s3cr3t",
+			want: models.Principal{Subject: "billing", Roles: []string{"admin"}, Method: models.AuthMethodAPIKey, TenantID: "acme"},
+		},
+		{name: "unknown key", key: "guess", wantErr: true},
+		// В заголовке передаётся сам ключ, а не его хеш из конфигурации
+		{name: "hash instead of key", key: hex.EncodeToString(sum[:]), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.AuthenticateAPIKey(tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("AuthenticateAPIKey() error = %v, want ErrUnauthenticated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateAPIKey() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthenticateAPIKey() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Auth
+		wantErr bool
+	}{
+		{name: "secret only", cfg: config.Auth{JWTSecret: testSecret}},
+		{name: "nothing configured", cfg: config.Auth{}, wantErr: true},
+		{name: "malformed API key", cfg: config.Auth{APIKeys: []string{"billing:not-a-hash"}}, wantErr: true},
+		{name: "missing JWKS file", cfg: config.Auth{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(context.Background(), tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
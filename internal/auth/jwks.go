@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minJWKSReload ограничивает перечитывание ключей из-за неизвестного kid,
+// чтобы поддельные токены не превращались в поток запросов к источнику ключей
+const minJWKSReload = time.Minute
+
+// jwksFetchTimeout ограничивает загрузку ключей по URL
+const jwksFetchTimeout = 10 * time.Second
+
+// jwk — открытый ключ в формате RFC 7517; поддерживаются только ключи RSA
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet хранит ключи RS256 из файла или по URL и периодически перечитывает их
+type keySet struct {
+	file    string
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]*rsa.PublicKey
+	loadedAt time.Time
+}
+
+func newKeySet(ctx context.Context, file, url string, refresh time.Duration) (*keySet, error) {
+	ks := &keySet{
+		file:    file,
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+	}
+	if err := ks.load(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// key возвращает ключ по kid. Пустой kid допустим, если в наборе один ключ.
+// Устаревший набор или неизвестный kid приводят к перечитыванию ключей.
+func (ks *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	ks.mu.RLock()
+	key, ok := ks.lookup(kid)
+	age := time.Since(ks.loadedAt)
+	ks.mu.RUnlock()
+
+	if (ok && age < ks.refresh) || (!ok && age < minJWKSReload) {
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	}
+
+	if err := ks.load(ctx); err != nil {
+		// Если источник ключей недоступен, продолжаем работать с прежним набором
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (ks *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (ks *keySet) load(ctx context.Context) error {
+	data, err := ks.read(ctx)
+	if err != nil {
+		return fmt.Errorf("auth: failed to read JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("auth: invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("auth: invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("auth: JWKS contains no RSA signing keys")
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.loadedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *keySet) read(ctx context.Context) ([]byte, error) {
+	if ks.file != "" {
+		return os.ReadFile(ks.file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("unsupported exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/caarlos0/env/v9"
 	"github.com/joho/godotenv"
@@ -12,8 +13,41 @@ import (
 // Config содержит конфигурацию приложения
 type Config struct {
 	Port   string `env:"APP_PORT" envDefault:"8080"`
+	HTTP   HTTP   `envPrefix:"HTTP_"`
 	DB     DB     `envPrefix:"DB_"`
 	Logger Logger `envPrefix:"LOGGER_"`
+	Trace  Trace  `envPrefix:"OTEL_"`
+	Auth   Auth   `envPrefix:"AUTH_"`
+	// RateLimit задаёт ограничения частоты запросов к API
+	RateLimit RateLimit `envPrefix:"RATE_LIMIT_"`
+}
+
+// HTTP содержит параметры поведения HTTP API
+type HTTP struct {
+	// RequireIfMatch включает строгий режим: PUT, PATCH и DELETE без заголовка
+	// If-Match отклоняются с 428 Precondition Required
+	RequireIfMatch bool `env:"REQUIRE_IF_MATCH" envDefault:"false"`
+	// IdempotencyTTL — срок хранения ответов на запросы с заголовком Idempotency-Key
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	// IdempotencyLease — сколько ключ закреплён за выполняющимся запросом. Если запрос
+	// не завершился за это время, повтор с тем же ключом выполняется заново.
+	// Должен превышать время обработки запроса.
+	IdempotencyLease time.Duration `env:"IDEMPOTENCY_LEASE" envDefault:"1m"`
+	// ReadinessTimeout ограничивает проверку базы в /readyz
+	ReadinessTimeout time.Duration `env:"READINESS_TIMEOUT" envDefault:"2s"`
+	// ShutdownDrainDelay — пауза между переводом /readyz в 503 и остановкой сервера,
+	// за которую балансировщик успевает исключить экземпляр
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
+	// TenantHeader — заголовок, которым роль platform_admin выбирает арендатора,
+	// если он не задан в её учётных данных
+	TenantHeader string `env:"TENANT_HEADER" envDefault:"X-Tenant-ID"`
+	// DefaultTenant используется, если арендатор не указан ни в учётных данных, ни в заголовке.
+	// Пустое значение делает указание арендатора обязательным.
+	DefaultTenant string `env:"DEFAULT_TENANT" envDefault:"default"`
+	// TrustedProxies — адреса и подсети (CIDR) прокси, которым разрешено передавать
+	// адрес клиента в X-Forwarded-For и X-Real-IP, через запятую. По умолчанию
+	// заголовкам не доверяют и адрес клиента берётся из соединения.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
 // DB содержит параметры подключения к базе данных
@@ -24,6 +58,96 @@ type DB struct {
 	Password string `env:"PASSWORD"`
 	Dbname   string `env:"NAME" envDefault:"myapp"`
 	Sslmode  string `env:"SSLMODE" envDefault:"disable"`
+	// MigrationUsername и MigrationPassword — владелец схемы, от имени которого
+	// применяются миграции. Сервис подключается ролью Username без владения таблицами,
+	// иначе политики RLS на него не действуют. Пустое значение — миграции
+	// выполняются ролью сервиса.
+	MigrationUsername string `env:"MIGRATION_USERNAME"`
+	MigrationPassword string `env:"MIGRATION_PASSWORD"`
+	// Пути к сертификатам для sslmode=verify-ca/verify-full и клиентской аутентификации по сертификату
+	SSLRootCert string `env:"SSLROOTCERT"`
+	SSLCert     string `env:"SSLCERT"`
+	SSLKey      string `env:"SSLKEY"`
+	// ApplicationName отображается в pg_stat_activity и логах Postgres
+	ApplicationName string `env:"APPLICATION_NAME" envDefault:"subscription-api"`
+	// ConnectTimeout ограничивает установку одного соединения (округляется до секунд)
+	ConnectTimeout time.Duration `env:"CONNECT_TIMEOUT" envDefault:"5s"`
+	// Параметры пула соединений. MaxOpenConns = 0 снимает ограничение на число соединений.
+	MaxOpenConns    int           `env:"MAX_OPEN_CONNS" envDefault:"25"`
+	MaxIdleConns    int           `env:"MAX_IDLE_CONNS" envDefault:"10"`
+	ConnMaxLifetime time.Duration `env:"CONN_MAX_LIFETIME" envDefault:"30m"`
+	ConnMaxIdleTime time.Duration `env:"CONN_MAX_IDLE_TIME" envDefault:"5m"`
+	// AutoMigrate применяет встроенные миграции при запуске сервиса
+	AutoMigrate bool `env:"AUTO_MIGRATE" envDefault:"false"`
+	// StatementTimeout ограничивает время выполнения одного запроса к базе (0 — без ограничения)
+	StatementTimeout time.Duration `env:"STATEMENT_TIMEOUT" envDefault:"5s"`
+	// ConnectAttempts и ConnectBackoff задают число попыток подключения при запуске
+	// и начальную паузу между ними; пауза удваивается после каждой неудачной попытки
+	ConnectAttempts int           `env:"CONNECT_ATTEMPTS" envDefault:"5"`
+	ConnectBackoff  time.Duration `env:"CONNECT_BACKOFF" envDefault:"1s"`
+}
+
+// Migration возвращает параметры подключения для применения миграций
+func (d DB) Migration() DB {
+	if d.MigrationUsername != "" {
+		d.Username, d.Password = d.MigrationUsername, d.MigrationPassword
+	}
+	return d
+}
+
+// Auth содержит параметры аутентификации. Должен быть задан хотя бы один способ:
+// секрет HS256, набор ключей JWKS или API-ключи.
+type Auth struct {
+	// Enabled = false отключает проверку: все запросы выполняются без принципала
+	Enabled bool `env:"ENABLED" envDefault:"true"`
+	// JWTSecret — общий секрет для токенов HS256
+	JWTSecret string `env:"JWT_HS256_SECRET"`
+	// JWKSFile или JWKSURL — открытые ключи RS256 в формате JWKS
+	JWKSFile    string        `env:"JWKS_FILE"`
+	JWKSURL     string        `env:"JWKS_URL"`
+	JWKSRefresh time.Duration `env:"JWKS_REFRESH" envDefault:"10m"`
+	// Issuer и Audience проверяются, если заданы
+	Issuer   string `env:"JWT_ISSUER"`
+	Audience string `env:"JWT_AUDIENCE"`
+	// Leeway — допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration `env:"JWT_LEEWAY" envDefault:"30s"`
+	// APIKeys — ключи для вызовов между сервисами в виде имя:sha256-хеш[:роль,роль[:арендатор]],
+	// записи разделяются точкой с запятой. В конфигурации хранится только хеш ключа.
+	// Ключ с арендатором обращается только к его подпискам.
+	APIKeys []string `env:"API_KEYS" envSeparator:";"`
+}
+
+// RateLimit содержит лимиты частоты запросов к API. Лимит записывается как
+// запросов/период[:burst], например 60/1m или 10/1s:20; без burst вызывающий может
+// потратить весь лимит периода подряд. Лимиты считаются отдельно для каждого
+// вызывающего: API-ключа, пользователя или, без аутентификации, IP-адреса клиента.
+type RateLimit struct {
+	Enabled bool `env:"ENABLED" envDefault:"true"`
+	// Store: memory — лимиты в памяти процесса (один экземпляр сервиса),
+	// postgres — общие для всех экземпляров
+	Store string `env:"STORE" envDefault:"memory"`
+	// Address — лимит запросов к API с одного IP-адреса клиента. Проверяется до
+	// аутентификации и ограничивает в том числе запросы с неверными учётными данными;
+	// пустое значение его отключает.
+	Address string `env:"ADDRESS" envDefault:"1200/1m"`
+	// Default — лимит маршрутов, не перечисленных в Routes; пустое значение их не ограничивает
+	Default string `env:"DEFAULT" envDefault:"600/1m"`
+	// Routes — лимиты отдельных маршрутов в виде МЕТОД /шаблон=лимит,
+	// записи разделяются точкой с запятой
+	Routes []string `env:"ROUTES" envSeparator:";" envDefault:"POST /subscription/cost=60/1m;GET /subscription/cost=60/1m;POST /subscription/cost/breakdown=60/1m;GET /subscription/cost/breakdown=60/1m"`
+}
+
+// Trace содержит параметры трассировки OpenTelemetry. Имена переменных
+// совпадают со стандартными переменными окружения OpenTelemetry.
+type Trace struct {
+	// Exporter: otlp — отправка по OTLP/HTTP, stdout — вывод спанов в консоль,
+	// none — спаны создаются (trace_id попадает в логи и ответы), но никуда не отправляются
+	Exporter    string `env:"TRACES_EXPORTER" envDefault:"none"`
+	Endpoint    string `env:"EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	ServiceName string `env:"SERVICE_NAME" envDefault:"subscription-api"`
+	// SampleRatio — доля трассировок, начинающихся в сервисе, которые записываются.
+	// Решение вызывающего из traceparent соблюдается всегда.
+	SampleRatio float64 `env:"TRACES_SAMPLER_ARG" envDefault:"1"`
 }
 
 // Config для логгера
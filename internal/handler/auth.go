@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey — ключ принципала в контексте Gin
+const principalKey = "principal"
+
+// apiKeyHeader — заголовок с API-ключом для вызовов между сервисами
+const apiKeyHeader = "X-API-Key"
+
+// authenticate проверяет Bearer-токен из Authorization или API-ключ из X-API-Key
+// и кладёт принципала в контекст запроса. Без учётных данных или с неверными
+// учётными данными запрос отклоняется с 401.
+func (h *Handler) authenticate(c *gin.Context) {
+	if h.auth == nil {
+		c.Next()
+		return
+	}
+
+	logger := h.getRequestLogger(c)
+
+	var (
+		principal models.Principal
+		err       error
+	)
+	switch {
+	case c.GetHeader(apiKeyHeader) != "":
+		principal, err = h.auth.AuthenticateAPIKey(c.GetHeader(apiKeyHeader))
+	case c.GetHeader("Authorization") != "":
+		scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			logger.Warn("unsupported authorization scheme")
+			h.unauthorized(c, "authorization header must use the Bearer scheme")
+			return
+		}
+		principal, err = h.auth.AuthenticateToken(c.Request.Context(), token)
+	default:
+		h.unauthorized(c, "authentication required")
+		return
+	}
+	if err != nil {
+		logger.Warn("authentication failed", "error", err)
+		h.unauthorized(c, "invalid credentials")
+		return
+	}
+
+	// Принципал нужен и сервисному слою, который проверяет права по контексту запроса
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(models.ContextWithPrincipal(c.Request.Context(), principal))
+	c.Set(loggerKey, logger.With("principal", principal.Subject, "auth_method", principal.Method))
+	c.Next()
+}
+
+func (h *Handler) unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="subscription-api"`)
+	newErrorResponse(c, http.StatusUnauthorized, msg)
+}
+
+// getPrincipal возвращает принципала, сохранённого authenticate
+func getPrincipal(c *gin.Context) (models.Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return models.Principal{}, false
+	}
+	principal, ok := value.(models.Principal)
+	return principal, ok
+}
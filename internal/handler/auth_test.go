@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/auth"
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sum := sha256.Sum256([]byte("s3cr3t"))
+	authenticator, err := auth.New(context.Background(), config.Auth{
+		JWTSecret: "test-secret",
+		APIKeys:   []string{"billing:" + hex.EncodeToString(sum[:])},
+	})
+	if err != nil {
+		t.Fatalf("auth.New() unexpected error: %v", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("SignedString() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		apiKey        string
+		wantStatus    int
+	}{
+		{name: "bearer token", authorization: "Bearer " + token, wantStatus: http.StatusNoContent},
+		{name: "scheme is case-insensitive", authorization: "bearer " + token, wantStatus: http.StatusNoContent},
+		{name: "API key", apiKey: "s3cr3t", wantStatus: http.StatusNoContent},
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+		{name: "basic scheme", authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "token without scheme", authorization: token, wantStatus: http.StatusUnauthorized},
+		{name: "empty bearer token", authorization: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer " + token + "x", wantStatus: http.StatusUnauthorized},
+		{name: "unknown API key", apiKey: "guess", wantStatus: http.StatusUnauthorized},
+		// API-ключ проверяется первым и не подменяется действительным токеном
+		{name: "unknown API key with a valid token", authorization: "Bearer " + token, apiKey: "guess", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), auth: authenticator}
+			router := gin.New()
+			router.GET("/", h.authenticate, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 response has no WWW-Authenticate header")
+			}
+		})
+	}
+}
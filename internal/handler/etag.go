@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// subscriptionETag формирует сильный ETag подписки вида "<версия>.<хеш>". Хеш
+// представления меняется и без новой версии, когда меняются значения, вычисляемые
+// на текущую дату: state, in_trial, действующая цена. If-Match сравнивает только версию.
+func subscriptionETag(s models.Subscription) string {
+	body, err := json.Marshal(s)
+	if err != nil {
+		// Подписка всегда сериализуется; без хеша ETag остаётся пригодным для If-Match
+		return `"` + strconv.FormatInt(s.Version, 10) + `"`
+	}
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(s.Version, 10) + "." + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagVersion извлекает версию подписки из ETag, выданного subscriptionETag
+func etagVersion(tag string) (int64, error) {
+	version, _, _ := strings.Cut(strings.Trim(tag, `"`), ".")
+	return strconv.ParseInt(version, 10, 64)
+}
+
+// splitETags разбирает список сущностных тегов из заголовков If-Match / If-None-Match
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ifMatchVersions разбирает заголовок If-Match и возвращает версии из ETag, при которых
+// разрешено изменение. Пустой результат без ошибки означает «без проверки версии»:
+// заголовок отсутствует в нестрогом режиме или равен "*".
+// При ошибке ответ уже записан и обработку нужно прекратить.
+func (h *Handler) ifMatchVersions(c *gin.Context) ([]int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if h.cfg.RequireIfMatch {
+			h.getRequestLogger(c).Warn("missing If-Match header")
+			newErrorResponse(c, http.StatusPreconditionRequired, "If-Match header is required")
+			return nil, false
+		}
+		return nil, true
+	}
+
+	var versions []int64
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			return nil, true
+		}
+		// If-Match использует строгое сравнение: слабые теги никогда не совпадают
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := etagVersion(tag)
+		if err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	if len(versions) == 0 {
+		h.getRequestLogger(c).Warn("If-Match does not match any version", "if_match", header)
+		newErrorResponse(c, http.StatusPreconditionFailed, "subscription version does not match If-Match")
+		return nil, false
+	}
+	return versions, true
+}
+
+// notModified выставляет ETag и, если он совпадает с If-None-Match, отвечает 304.
+// If-None-Match использует слабое сравнение тегов.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWithETag отвечает JSON со слабым ETag, вычисленным по телу ответа,
+// либо 304, если клиент уже располагает этим представлением.
+// Используется для списков, у которых нет собственной версии.
+func jsonWithETag(c *gin.Context, obj any) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	if notModified(c, `W/"`+hex.EncodeToString(sum[:16])+`"`) {
+		return
+	}
+	c.Data(http.StatusOK, gin.MIMEJSON+"; charset=utf-8", body)
+}
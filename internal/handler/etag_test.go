@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatchVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		header       string
+		strict       bool
+		wantVersions []int64
+		wantOK       bool
+		wantStatus   int
+	}{
+		{name: "no header", wantOK: true},
+		{name: "no header in strict mode", strict: true, wantStatus: http.StatusPreconditionRequired},
+		{name: "any version", header: "*", wantOK: true},
+		{name: "version only", header: `"3"`, wantVersions: []int64{3}, wantOK: true},
+		{name: "version with hash", header: `"3.1a2b3c4d5e6f7a8b"`, wantVersions: []int64{3}, wantOK: true},
+		{name: "several tags", header: `"3.aa", "5.bb"`, wantVersions: []int64{3, 5}, wantOK: true},
+		{name: "star among tags", header: `"3", *`, wantOK: true},
+		{name: "weak tags are skipped", header: `W/"2", "4"`, wantVersions: []int64{4}, wantOK: true},
+		{name: "only weak tags", header: `W/"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "foreign tag", header: `"abc"`, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg:    config.HTTP{RequireIfMatch: tt.strict},
+			}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/subscription/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			versions, ok := h.ifMatchVersions(c)
+			if ok != tt.wantOK {
+				t.Fatalf("ifMatchVersions() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(versions, tt.wantVersions) {
+				t.Errorf("ifMatchVersions() = %v, want %v", versions, tt.wantVersions)
+			}
+			if !ok && w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSubscriptionETag(t *testing.T) {
+	base := models.Subscription{Version: 7, ServiceName: "Netflix", Price: 400, State: models.StateActive}
+	paused := base
+	paused.State = models.StatePaused
+
+	tag := subscriptionETag(base)
+	tests := []struct {
+		name     string
+		sub      models.Subscription
+		wantSame bool
+	}{
+		{name: "same representation", sub: base, wantSame: true},
+		{name: "computed field changed", sub: paused, wantSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subscriptionETag(tt.sub)
+			if (got == tag) != tt.wantSame {
+				t.Errorf("subscriptionETag() = %s, base %s, want same = %v", got, tag, tt.wantSame)
+			}
+			version, err := etagVersion(got)
+			if err != nil || version != tt.sub.Version {
+				t.Errorf("etagVersion(%s) = %d, %v, want %d", got, version, err, tt.sub.Version)
+			}
+		})
+	}
+}
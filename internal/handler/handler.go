@@ -3,45 +3,118 @@ package handler
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/BountyM/effectiveMobileTestTask/internal/auth"
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/BountyM/effectiveMobileTestTask/internal/ratelimit"
 	"github.com/BountyM/effectiveMobileTestTask/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ключи значений в контексте Gin
+const (
+	loggerKey    = "logger"
+	requestIDKey = "request_id"
 )
 
 type Handler struct {
 	services *service.Service
 	logger   *slog.Logger
+	cfg      config.HTTP
+	metrics  *metrics.Metrics
+	// auth проверяет учётные данные; nil — аутентификация отключена
+	auth *auth.Authenticator
+	// limiter ограничивает частоту запросов; nil — без ограничений
+	limiter *ratelimit.Limiter
+	// serviceName — имя сервиса в спанах HTTP-запросов
+	serviceName string
+	// draining выставляется при остановке сервиса, см. StartDraining
+	draining atomic.Bool
 }
 
-func New(services *service.Service, logger *slog.Logger) *Handler {
+func New(services *service.Service, logger *slog.Logger, cfg config.HTTP, metrics *metrics.Metrics,
+	authenticator *auth.Authenticator, limiter *ratelimit.Limiter, serviceName string) *Handler {
 	return &Handler{
-		services: services,
-		logger:   logger,
+		services:    services,
+		logger:      logger,
+		cfg:         cfg,
+		metrics:     metrics,
+		auth:        authenticator,
+		limiter:     limiter,
+		serviceName: serviceName,
 	}
 }
 
-func (h *Handler) InitRoutes() *gin.Engine {
+func (h *Handler) InitRoutes() (*gin.Engine, error) {
 	router := gin.New()
+	// Адрес клиента из X-Forwarded-For принимается только от доверенных прокси:
+	// иначе клиент выбирал бы его сам и обходил лимит запросов с адреса
+	if err := router.SetTrustedProxies(h.cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 	// Добавляем middleware
-	router.Use(gin.Recovery())      // Стандартный recovery middleware
+	router.Use(gin.Recovery()) // Стандартный recovery middleware
+	// Спан запроса создаётся до логгера, чтобы trace_id попал в логи запроса.
+	// Входящий traceparent продолжает трассировку вызывающего.
+	router.Use(otelgin.Middleware(h.serviceName, otelgin.WithGinFilter(shouldTrace)))
 	router.Use(h.loggingMiddleware) // Ваш кастомный logging middleware
+	router.Use(h.metricsMiddleware)
+
+	// Неизвестные маршруты отвечают ошибкой в общем формате
+	router.NoRoute(func(c *gin.Context) {
+		newErrorResponse(c, http.StatusNotFound, "route not found")
+	})
+
+	// Проверки живости и готовности для оркестратора
+	router.GET("/healthz", h.healthz)
+	router.GET("/readyz", h.readyz)
+	// Метрики в формате Prometheus
+	router.GET("/metrics", gin.WrapH(h.metrics.Handler()))
 
 	// Swagger UI: доступен по /swagger/index.html
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	subscription := router.Group("/subscription")
-	subscription.POST("/", h.createSubscription)
-	subscription.GET("/:user_id/:page/:limit", h.getSubscriptions)
-	subscription.GET("/:user_id", h.getSubscriptions)
+	// Маршруты API доступны только аутентифицированным вызывающим в пределах их арендатора.
+	// Лимит адреса проверяется до аутентификации и ограничивает запросы с неверными
+	// учётными данными, лимит маршрута — после неё, чтобы считать запросы по вызывающему.
+	api := router.Group("/", h.rateLimitAddress, h.authenticate, h.rateLimit, h.resolveTenant)
+
+	// Операции над одной подпиской адресуются только по её ID
+	subscription := api.Group("/subscription")
+	subscription.POST("/", h.idempotency, h.createSubscription)
+	subscription.GET("/:id", h.getSubscription)
+	// Устаревший маршрут списка подписок пользователя, см. getSubscriptionsLegacy
+	subscription.GET("/:id/:page/:limit", h.getSubscriptionsLegacy)
 	subscription.DELETE("/:id", h.deleteSubscription)
 	subscription.PUT("/:id", h.updateSubscription)
+	subscription.PATCH("/:id", h.patchSubscription)
+	subscription.POST("/:id/prices", h.setSubscriptionPrice)
+	subscription.POST("/:id/pause", h.pauseSubscription)
+	subscription.POST("/:id/resume", h.resumeSubscription)
+	subscription.DELETE("/:id/pause/:start_month", h.cancelPause)
+	subscription.POST("/:id/cancel", h.cancelSubscription)
+	subscription.POST("/:id/uncancel", h.uncancelSubscription)
+	subscription.POST("/cancellations/reasons", h.getCancellationReasons)
+	subscription.POST("/cost", h.getCost)
+	subscription.POST("/cost/breakdown", h.getCostBreakdown)
+	// GET с телом запроса оставлен для совместимости со старыми клиентами
 	subscription.GET("/cost", h.getCost)
+	subscription.GET("/cost/breakdown", h.getCostBreakdown)
 
-	return router
+	// Списки подписок
+	api.GET("/subscriptions", h.listSubscriptions)
+	api.GET("/users/:user_id/subscriptions", h.getSubscriptions)
+
+	return router, nil
 }
 
 // Middleware для логирования
@@ -54,9 +127,16 @@ func (h *Handler) loggingMiddleware(c *gin.Context) {
 
 	// Создаём логгер с request_id для этого запроса
 	reqLogger := h.logger.With(slog.String("request_id", requestID))
+	if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+		reqLogger = reqLogger.With(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
 
-	// Сохраняем логгер в контексте Gin
-	c.Set("logger", reqLogger)
+	// Сохраняем логгер и request_id в контексте Gin
+	c.Set(loggerKey, reqLogger)
+	c.Set(requestIDKey, requestID)
 
 	start := time.Now()
 	// Продолжаем обработку запроса
@@ -75,6 +155,16 @@ func (h *Handler) loggingMiddleware(c *gin.Context) {
 	)
 }
 
+// shouldTrace сообщает, нужно ли трассировать запрос. Проверки состояния и сбор
+// метрик не трассируются: оркестратор и Prometheus вызывают их постоянно.
+func shouldTrace(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
 // Функция генерации request_id
 func generateRequestID() string {
 	id, err := uuid.NewRandom()
@@ -87,7 +177,7 @@ func generateRequestID() string {
 
 // getRequestLogger получает логгер из контекста запроса
 func (h *Handler) getRequestLogger(c *gin.Context) *slog.Logger {
-	if logger, exists := c.Get("logger"); exists {
+	if logger, exists := c.Get(loggerKey); exists {
 		if reqLogger, ok := logger.(*slog.Logger); ok {
 			return reqLogger
 		}
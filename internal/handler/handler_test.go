@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func TestShouldTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		route string
+		want  bool
+	}{
+		{route: "/healthz", want: false},
+		{route: "/readyz", want: false},
+		{route: "/metrics", want: false},
+		{route: "/subscription/:id", want: true},
+		{route: "/subscriptions", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			var got bool
+			router := gin.New()
+			router.GET(tt.route, func(c *gin.Context) { got = shouldTrace(c) })
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.route, nil))
+			if got != tt.want {
+				t.Errorf("shouldTrace(%s) = %v, want %v", tt.route, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := config.HTTP{TenantHeader: "X-Tenant-ID", DefaultTenant: "default"}
+	// Запросы отклоняются обработчиками до обращения к сервису, поэтому он не нужен
+	router, err := New(nil, logger, cfg, metrics.New(logger), nil, nil, "test").InitRoutes()
+	if err != nil {
+		t.Fatalf("InitRoutes() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		wantStatus     int
+		wantDetail     string
+		wantDeprecated bool
+		wantLink       string
+	}{
+		{name: "subscription by id", path: "/subscription/42", wantStatus: http.StatusBadRequest, wantDetail: "invalid subscription id"},
+		{name: "user subscriptions", path: "/users/42/subscriptions", wantStatus: http.StatusBadRequest, wantDetail: "invalid user_id format"},
+		{
+			name:           "deprecated paged user route",
+			path:           "/subscription/42/2/20",
+			wantStatus:     http.StatusBadRequest,
+			wantDetail:     "invalid user_id format",
+			wantDeprecated: true,
+			wantLink:       `</users/42/subscriptions?limit=20&page=2>; rel="successor-version"`,
+		},
+		{name: "unknown route", path: "/subscription/42/2", wantStatus: http.StatusNotFound, wantDetail: "route not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+			var problem problemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil || problem.Detail != tt.wantDetail {
+				t.Errorf("GET %s detail = %q (%v), want %q", tt.path, problem.Detail, err, tt.wantDetail)
+			}
+			if got := w.Header().Get("Deprecation") != ""; got != tt.wantDeprecated {
+				t.Errorf("GET %s Deprecation header present = %v, want %v", tt.path, got, tt.wantDeprecated)
+			}
+			if got := w.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("GET %s Link = %q, want %q", tt.path, got, tt.wantLink)
+			}
+		})
+	}
+}
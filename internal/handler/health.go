@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Состояния, возвращаемые проверками
+const (
+	statusOK          = "ok"
+	statusDraining    = "draining"
+	statusUnavailable = "unavailable"
+)
+
+// HealthResponse model
+// Ответ проверки живости
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse model
+// Ответ проверки готовности
+type readinessResponse struct {
+	Status string `json:"status"`
+	// Database — результат проверки базы: ok или unavailable. Подробности ошибки
+	// только в журнале: маршрут открыт без аутентификации
+	Database string `json:"database,omitempty"`
+	models.Readiness
+}
+
+// StartDraining переводит /readyz в состояние 503, чтобы балансировщик перестал
+// направлять запросы на экземпляр до остановки сервера. Остальные маршруты
+// продолжают обслуживать уже направленные запросы.
+func (h *Handler) StartDraining() {
+	h.draining.Store(true)
+}
+
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс работает. Зависимости не проверяются.
+// @Tags health
+// @Produce json
+// @Success 200 {object} healthResponse
+// @Router /healthz [get]
+func (h *Handler) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// @Summary Проверка готовности
+// @Description Проверяет соединение с базой за ограниченное время и возвращает версию схемы и сведения о сборке.
+// @Description Во время остановки сервиса отвечает 503 со статусом draining.
+// @Tags health
+// @Produce json
+// @Success 200 {object} readinessResponse
+// @Failure 503 {object} readinessResponse "База недоступна или сервис останавливается"
+// @Router /readyz [get]
+func (h *Handler) readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, readinessResponse{Status: statusDraining})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.cfg.ReadinessTimeout)
+	defer cancel()
+
+	readiness, err := h.services.Health.Ready(ctx)
+	if err != nil {
+		h.getRequestLogger(c).Warn("readiness check failed", "error", err)
+		c.JSON(http.StatusServiceUnavailable, readinessResponse{
+			Status:    statusUnavailable,
+			Database:  statusUnavailable,
+			Readiness: readiness,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, readinessResponse{
+		Status:    statusOK,
+		Database:  statusOK,
+		Readiness: readiness,
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/BountyM/effectiveMobileTestTask/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeHealth struct {
+	readiness models.Readiness
+	err       error
+}
+
+func (f fakeHealth) Ready(context.Context) (models.Readiness, error) {
+	return f.readiness, f.err
+}
+
+func TestHealthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	build := models.BuildInfo{Version: "1.2.3", GoVersion: "go1.24"}
+	tests := []struct {
+		name         string
+		path         string
+		health       fakeHealth
+		draining     bool
+		wantStatus   int
+		wantBody     string
+		wantDatabase string
+		wantVersion  int64
+	}{
+		{name: "liveness", path: "/healthz", wantStatus: http.StatusOK, wantBody: statusOK},
+		{
+			name:         "ready",
+			path:         "/readyz",
+			health:       fakeHealth{readiness: models.Readiness{SchemaVersion: 7, Build: build}},
+			wantStatus:   http.StatusOK,
+			wantBody:     statusOK,
+			wantDatabase: statusOK,
+			wantVersion:  7,
+		},
+		{
+			name:         "database unavailable",
+			path:         "/readyz",
+			health:       fakeHealth{readiness: models.Readiness{Build: build}, err: errors.New("connection refused")},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantBody:     statusUnavailable,
+			wantDatabase: statusUnavailable,
+		},
+		{
+			name:       "draining",
+			path:       "/readyz",
+			health:     fakeHealth{readiness: models.Readiness{SchemaVersion: 7, Build: build}},
+			draining:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   statusDraining,
+		},
+		{
+			name:       "liveness while draining",
+			path:       "/healthz",
+			draining:   true,
+			wantStatus: http.StatusOK,
+			wantBody:   statusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg := config.HTTP{ReadinessTimeout: time.Second}
+			h := New(&service.Service{Health: tt.health}, logger, cfg, metrics.New(logger), nil, nil, "test")
+			if tt.draining {
+				h.StartDraining()
+			}
+			router, err := h.InitRoutes()
+			if err != nil {
+				t.Fatalf("InitRoutes() unexpected error: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+			var got readinessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET %s body %q: %v", tt.path, w.Body.String(), err)
+			}
+			if got.Status != tt.wantBody {
+				t.Errorf("GET %s status field = %q, want %q", tt.path, got.Status, tt.wantBody)
+			}
+			if got.Database != tt.wantDatabase {
+				t.Errorf("GET %s database = %q, want %q", tt.path, got.Database, tt.wantDatabase)
+			}
+			if got.SchemaVersion != tt.wantVersion {
+				t.Errorf("GET %s schema_version = %d, want %d", tt.path, got.SchemaVersion, tt.wantVersion)
+			}
+			if tt.wantDatabase != "" && got.Build.Version != build.Version {
+				t.Errorf("GET %s build version = %q, want %q", tt.path, got.Build.Version, build.Version)
+			}
+		})
+	}
+}
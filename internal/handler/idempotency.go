@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKeyHeader — заголовок, которым клиент помечает повторяемый запрос
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength ограничивает длину ключа размером столбца в таблице
+const maxIdempotencyKeyLength = 255
+
+// bodyRecorder копирует тело ответа, чтобы сохранить его для повторов
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotency делает запрос с заголовком Idempotency-Key повторяемым:
+// ответ на первый запрос сохраняется на время cfg.IdempotencyTTL и отдаётся
+// на повторы с тем же ключом и телом. Повтор ключа с другим телом отклоняется с 422,
+// повтор во время выполнения первого запроса — с 409, пока не истекла аренда ключа
+// cfg.IdempotencyLease. Ответы 5xx не сохраняются, чтобы клиент мог повторить
+// запрос после сбоя.
+func (h *Handler) idempotency(c *gin.Context) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	logger := h.getRequestLogger(c)
+	if len(key) > maxIdempotencyKeyLength {
+		logger.Warn("idempotency key too long", "length", len(key))
+		newErrorResponse(c, http.StatusBadRequest, "Idempotency-Key must not exceed 255 characters")
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger.Warn("failed to read request body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	scope := idempotencyScope(c)
+	// Postgres хранит время с точностью до микросекунды, а LockedUntil служит
+	// признаком владения ключом в Complete и Release
+	now := time.Now().Truncate(time.Microsecond)
+	record := models.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: requestFingerprint(scope, body),
+		ExpiresAt:   now.Add(h.cfg.IdempotencyTTL),
+		LockedUntil: now.Add(h.cfg.IdempotencyLease),
+	}
+
+	existing, err := h.services.Idempotency.Begin(c.Request.Context(), record)
+	if err != nil {
+		h.handleError(c, "failed to reserve idempotency key", err)
+		return
+	}
+	if existing != nil {
+		if existing.StatusCode == 0 {
+			logger.Warn("request with idempotency key is in progress", "idempotency_key", key)
+			// После истечения аренды повтор выполнит запрос заново
+			c.Header("Retry-After", strconv.FormatInt(max(ceilSeconds(time.Until(existing.LockedUntil)), 1), 10))
+			newErrorResponse(c, http.StatusConflict, "request with this Idempotency-Key is still in progress")
+			return
+		}
+		logger.Info("replaying idempotent response", "idempotency_key", key)
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+		c.Abort()
+		return
+	}
+
+	recorder := &bodyRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	// Клиент мог разорвать соединение, не дождавшись ответа; ключ всё равно
+	// нужно освободить или сохранить, поэтому отмену запроса не наследуем
+	ctx := context.WithoutCancel(c.Request.Context())
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		if err := h.services.Idempotency.Release(ctx, record); err != nil {
+			logger.Error("failed to release idempotency key", "error", err)
+		}
+		return
+	}
+
+	record.StatusCode = status
+	record.ContentType = recorder.Header().Get("Content-Type")
+	record.Body = recorder.body.Bytes()
+	if err := h.services.Idempotency.Complete(ctx, record); err != nil {
+		logger.Error("failed to store idempotent response", "error", err)
+	}
+}
+
+// idempotencyScope возвращает область действия ключа: маршрут, арендатор и вызывающий.
+// Один и тот же ключ у разных пользователей не пересекается и не отдаёт чужой ответ.
+func idempotencyScope(c *gin.Context) string {
+	scope := c.Request.Method + " " + c.FullPath() + " " + c.GetString(tenantKey)
+	if principal, ok := getPrincipal(c); ok {
+		caller := sha256.Sum256([]byte(principal.Method + ":" + principal.Subject))
+		scope += " " + hex.EncodeToString(caller[:])
+	}
+	return scope
+}
+
+// requestFingerprint возвращает хеш запроса, по которому повтор ключа с другим
+// телом отличается от настоящего повтора
+func requestFingerprint(scope string, body []byte) string {
+	fingerprint := sha256.Sum256(append([]byte(scope+"\n"), body...))
+	return hex.EncodeToString(fingerprint[:])
+}
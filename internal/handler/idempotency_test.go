@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotencyScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := &models.Principal{Subject: "60601fee-2bf1-4721-ae6f-7636e79a0cba", Method: models.AuthMethodJWT}
+	other := &models.Principal{Subject: "0b3bd2a4-6f47-4a53-9bd0-0f5c1a2e7d11", Method: models.AuthMethodJWT}
+	// Имя API-ключа может совпасть с subject пользователя
+	apiKey := &models.Principal{Subject: user.Subject, Method: models.AuthMethodAPIKey}
+
+	scope := func(method, tenant string, principal *models.Principal) string {
+		var got string
+		router := gin.New()
+		router.Handle(method, "/subscription/", func(c *gin.Context) {
+			c.Set(tenantKey, tenant)
+			if principal != nil {
+				c.Set(principalKey, *principal)
+			}
+			got = idempotencyScope(c)
+		})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/subscription/", nil))
+		return got
+	}
+	base := scope(http.MethodPost, "acme", user)
+
+	tests := []struct {
+		name     string
+		scope    string
+		wantSame bool
+	}{
+		{name: "same caller", scope: scope(http.MethodPost, "acme", user), wantSame: true},
+		{name: "another user", scope: scope(http.MethodPost, "acme", other)},
+		{name: "API key with the same name", scope: scope(http.MethodPost, "acme", apiKey)},
+		{name: "another tenant", scope: scope(http.MethodPost, "globex", user)},
+		{name: "another method", scope: scope(http.MethodPut, "acme", user)},
+		{name: "no principal", scope: scope(http.MethodPost, "acme", nil)},
+	}
+
+	if !strings.HasPrefix(base, "POST /subscription/ acme ") {
+		t.Errorf("idempotencyScope() = %q, want method, route and tenant first", base)
+	}
+	if strings.Contains(base, user.Subject) {
+		t.Errorf("idempotencyScope() = %q, want the caller hashed", base)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.scope == base) != tt.wantSame {
+				t.Errorf("idempotencyScope() = %q, base %q, want same = %v", tt.scope, base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	const scope = "POST /subscription/ acme"
+	body := []byte(`{"service_name":"Netflix","price":400}`)
+	base := requestFingerprint(scope, body)
+
+	tests := []struct {
+		name     string
+		scope    string
+		body     []byte
+		wantSame bool
+	}{
+		{name: "same request", scope: scope, body: body, wantSame: true},
+		{name: "another body", scope: scope, body: []byte(`{"service_name":"Netflix","price":500}`)},
+		{name: "another scope", scope: "POST /subscription/ globex", body: body},
+	}
+
+	if len(base) != 64 {
+		t.Errorf("requestFingerprint() = %q, want a hex-encoded SHA-256", base)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestFingerprint(tt.scope, tt.body); (got == base) != tt.wantSame {
+				t.Errorf("requestFingerprint() = %s, base %s, want same = %v", got, base, tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute — метка маршрута для запросов, не попавших ни в один маршрут.
+// Исходный путь в метку не попадает, чтобы число рядов метрик не росло неограниченно.
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware считает запросы и время их обработки по шаблону маршрута
+func (h *Handler) metricsMiddleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	status := strconv.Itoa(c.Writer.Status())
+
+	h.metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+	h.metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).
+		Observe(time.Since(start).Seconds())
+}
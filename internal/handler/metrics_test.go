@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantRoute  string
+		wantStatus string
+	}{
+		{name: "matched route is labelled by template", path: "/subscription/42", wantRoute: "/subscription/:id", wantStatus: "204"},
+		{name: "unmatched route", path: "/subscription/42/extra", wantRoute: unmatchedRoute, wantStatus: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			h := &Handler{logger: logger, metrics: metrics.New(logger)}
+			router := gin.New()
+			router.Use(h.metricsMiddleware)
+			router.GET("/subscription/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var metric dto.Metric
+			counter := h.metrics.HTTPRequests.WithLabelValues(http.MethodGet, tt.wantRoute, tt.wantStatus)
+			if err := counter.Write(&metric); err != nil {
+				t.Fatalf("Write() unexpected error: %v", err)
+			}
+			if got := metric.GetCounter().GetValue(); got != 1 {
+				t.Errorf("http_requests{route=%q,status=%q} = %v, want 1", tt.wantRoute, tt.wantStatus, got)
+			}
+			// Исходный путь не должен порождать отдельный ряд
+			series := make(chan prometheus.Metric, 8)
+			h.metrics.HTTPRequests.Collect(series)
+			close(series)
+			if len(series) != 1 {
+				t.Errorf("http_requests has %d series, want 1", len(series))
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitAddress ограничивает частоту запросов с IP-адреса клиента. Он проверяется
+// до аутентификации, поэтому перебор учётных данных тоже упирается в лимит.
+func (h *Handler) rateLimitAddress(c *gin.Context) {
+	if h.limiter == nil {
+		c.Next()
+		return
+	}
+
+	res, ok, err := h.limiter.TakeAddress(c.Request.Context(), c.ClientIP())
+	h.applyLimit(c, res, ok, err)
+}
+
+// rateLimit ограничивает частоту запросов вызывающего к маршруту. Вызывающий
+// определяется по API-ключу или пользователю, без аутентификации — по IP-адресу.
+// Превышение лимита отклоняется с 429 и Retry-After; ответы на маршруты с лимитом
+// содержат заголовки RateLimit-* (draft-ietf-httpapi-ratelimit-headers).
+func (h *Handler) rateLimit(c *gin.Context) {
+	if h.limiter == nil {
+		c.Next()
+		return
+	}
+
+	identity := "ip:" + c.ClientIP()
+	if principal, ok := getPrincipal(c); ok {
+		identity = principal.Method + ":" + principal.Subject
+	}
+
+	res, ok, err := h.limiter.Take(c.Request.Context(), c.Request.Method+" "+c.FullPath(), identity)
+	h.applyLimit(c, res, ok, err)
+}
+
+// applyLimit пропускает запрос или отклоняет его с 429 по результату проверки лимита
+func (h *Handler) applyLimit(c *gin.Context, res ratelimit.Result, ok bool, err error) {
+	logger := h.getRequestLogger(c)
+
+	if err != nil {
+		// Недоступное хранилище лимитов не должно останавливать API
+		logger.Error("rate limit check failed, request is allowed", "error", err)
+		c.Next()
+		return
+	}
+	if !ok {
+		c.Next()
+		return
+	}
+
+	setRateLimitHeaders(c, res)
+	if !res.Allowed {
+		retryAfter := ceilSeconds(res.RetryAfter)
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		logger.Warn("rate limit exceeded", "retry_after", retryAfter)
+		newErrorResponse(c, http.StatusTooManyRequests,
+			fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter))
+		return
+	}
+	c.Next()
+}
+
+func setRateLimitHeaders(c *gin.Context, res ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d",
+		res.Limit.Requests, ceilSeconds(res.Limit.Period), res.Limit.Burst))
+}
+
+// ceilSeconds округляет длительность до целых секунд вверх, как требуют заголовки
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
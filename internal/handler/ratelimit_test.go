@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/BountyM/effectiveMobileTestTask/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const proxy = "203.0.113.7"
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		// wantStatus — статус второго запроса с другим X-Forwarded-For
+		wantStatus int
+	}{
+		// Лимит адреса исчерпан первым запросом: подмена заголовка не даёт новую корзину
+		{name: "forwarded header is ignored by default", wantStatus: http.StatusTooManyRequests},
+		{name: "forwarded header from an untrusted peer", trustedProxies: []string{"198.51.100.0/24"}, wantStatus: http.StatusTooManyRequests},
+		// За доверенным прокси клиенты различаются по X-Forwarded-For
+		{name: "forwarded header from a trusted proxy", trustedProxies: []string{proxy}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			limiter, err := ratelimit.New(config.RateLimit{Address: "1/1m"}, ratelimit.NewMemoryStore())
+			if err != nil {
+				t.Fatalf("ratelimit.New() unexpected error: %v", err)
+			}
+			cfg := config.HTTP{DefaultTenant: "default", TenantHeader: "X-Tenant-ID", TrustedProxies: tt.trustedProxies}
+			router, err := New(nil, logger, cfg, metrics.New(logger), nil, limiter, "test").InitRoutes()
+			if err != nil {
+				t.Fatalf("InitRoutes() unexpected error: %v", err)
+			}
+
+			var status int
+			for _, forwardedFor := range []string{"192.0.2.1", "192.0.2.2"} {
+				// Некорректный ID отклоняется обработчиком до обращения к сервису
+				req := httptest.NewRequest(http.MethodGet, "/subscription/not-a-uuid", nil)
+				req.RemoteAddr = proxy + ":40000"
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				status = w.Code
+			}
+			if status != tt.wantStatus {
+				t.Errorf("second request status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestInitRoutesInvalidTrustedProxy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := config.HTTP{TrustedProxies: []string{"not-an-address"}}
+	if _, err := New(nil, logger, cfg, metrics.New(logger), nil, nil, "test").InitRoutes(); err == nil {
+		t.Error("InitRoutes() error = nil, want error for an invalid trusted proxy")
+	}
+}
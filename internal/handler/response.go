@@ -1,11 +1,139 @@
 package handler
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"net/http"
 
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType — тип содержимого ответов об ошибках по RFC 7807
+const problemContentType = "application/problem+json"
+
+// problemTypeBase — префикс URI типа проблемы, к нему добавляется код ошибки
+const problemTypeBase = "/problems/"
+
+// ProblemDetails model
+// Ответ об ошибке в формате RFC 7807 (application/problem+json)
+type problemDetails struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+}
+
+// ErrorResponse model
+// Прежний формат ответа об ошибке, отдаётся клиентам с Accept: application/json
 type errorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Машиночитаемые коды ошибок, возвращаемые в поле code
+const (
+	codeBadRequest         = "bad_request"
+	codeUnauthorized       = "unauthorized"
+	codeForbidden          = "forbidden"
+	codeNotFound           = "not_found"
+	codeConflict           = "conflict"
+	codePreconditionFailed = "precondition_failed"
+	codePreconditionReq    = "precondition_required"
+	codeValidationFailed   = "validation_failed"
+	codeUnsupportedMedia   = "unsupported_media_type"
+	codeRateLimited        = "rate_limited"
+	codeServiceUnavailable = "service_unavailable"
+	codeInternalError      = "internal_error"
+)
+
+// errorCodes сопоставляет HTTP-статусы с кодами ошибок
+var errorCodes = map[int]string{
+	http.StatusBadRequest:           codeBadRequest,
+	http.StatusUnauthorized:         codeUnauthorized,
+	http.StatusForbidden:            codeForbidden,
+	http.StatusNotFound:             codeNotFound,
+	http.StatusConflict:             codeConflict,
+	http.StatusPreconditionFailed:   codePreconditionFailed,
+	http.StatusPreconditionRequired: codePreconditionReq,
+	http.StatusUnprocessableEntity:  codeValidationFailed,
+	http.StatusUnsupportedMediaType: codeUnsupportedMedia,
+	http.StatusTooManyRequests:      codeRateLimited,
+	http.StatusServiceUnavailable:   codeServiceUnavailable,
+	http.StatusInternalServerError:  codeInternalError,
 }
 
 func newErrorResponse(c *gin.Context, statusCode int, err string) {
-	c.AbortWithStatusJSON(statusCode, errorResponse{err})
+	writeError(c, statusCode, err, nil)
+}
+
+// writeError прерывает обработку запроса и пишет ответ об ошибке.
+// По умолчанию ответ формируется в формате application/problem+json;
+// клиенты, явно запросившие application/json, получают прежний формат errorResponse.
+func writeError(c *gin.Context, statusCode int, detail string, fields []models.FieldError) {
+	code, ok := errorCodes[statusCode]
+	if !ok {
+		code = codeInternalError
+	}
+
+	if c.NegotiateFormat(problemContentType, gin.MIMEJSON) == gin.MIMEJSON {
+		c.AbortWithStatusJSON(statusCode, errorResponse{Error: detail, Code: code})
+		return
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(statusCode, problemDetails{
+		Type:      problemTypeBase + code,
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString(requestIDKey),
+		Errors:    fields,
+	})
+}
+
+// handleError сопоставляет доменные ошибки из models с HTTP-статусами и пишет ответ.
+// Ошибки клиента логируются с уровнем Warn, ошибки сервера — с уровнем Error;
+// текст внутренних ошибок клиенту не передаётся.
+func (h *Handler) handleError(c *gin.Context, msg string, err error) {
+	logger := h.getRequestLogger(c)
+
+	var (
+		validationErr *models.ValidationError
+		conflictErr   *models.ConflictError
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		logger.Warn(msg, "error", err)
+		writeError(c, http.StatusUnprocessableEntity, validationErr.Error(), validationErr.Fields)
+	case errors.As(err, &conflictErr):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusConflict, conflictErr.Error())
+	case errors.Is(err, models.ErrValidation):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusUnprocessableEntity, "validation failed")
+	case errors.Is(err, models.ErrNotFound):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusNotFound, "subscription not found")
+	case errors.Is(err, models.ErrForbidden):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusForbidden, "operation is not allowed for the caller")
+	case errors.Is(err, models.ErrPreconditionFailed):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusPreconditionFailed, "subscription version does not match If-Match")
+	case errors.Is(err, models.ErrConflict):
+		logger.Warn(msg, "error", err)
+		newErrorResponse(c, http.StatusConflict, "conflict with current state")
+	case errors.Is(err, models.ErrUnavailable):
+		logger.Error(msg, "error", err)
+		newErrorResponse(c, http.StatusServiceUnavailable, "service temporarily unavailable")
+	default:
+		logger.Error(msg, "error", err)
+		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+	}
 }
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "validation error with fields",
+			err:        fmt.Errorf("SubscriptionService Create() %w", models.NewValidationError("price", "price must be positive")),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantDetail: "price must be positive",
+		},
+		{
+			name:       "validation error from the database",
+			err:        fmt.Errorf("%w: check violation", models.ErrValidation),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantDetail: "validation failed",
+		},
+		{
+			name:       "not found",
+			err:        fmt.Errorf("SubscriptionPostgres GetByID() %w", models.ErrNotFound),
+			wantStatus: http.StatusNotFound,
+			wantDetail: "subscription not found",
+		},
+		{name: "forbidden", err: models.ErrForbidden, wantStatus: http.StatusForbidden, wantDetail: "operation is not allowed for the caller"},
+		{
+			name:       "precondition failed",
+			err:        models.ErrPreconditionFailed,
+			wantStatus: http.StatusPreconditionFailed,
+			wantDetail: "subscription version does not match If-Match",
+		},
+		{
+			name:       "conflict with reason",
+			err:        models.NewConflictError("subscription is already cancelled"),
+			wantStatus: http.StatusConflict,
+			wantDetail: "subscription is already cancelled",
+		},
+		{name: "conflict", err: models.ErrConflict, wantStatus: http.StatusConflict, wantDetail: "conflict with current state"},
+		{name: "unavailable", err: models.ErrUnavailable, wantStatus: http.StatusServiceUnavailable, wantDetail: "service temporarily unavailable"},
+		// Текст внутренней ошибки клиенту не передаётся
+		{name: "internal error", err: errors.New("pq: relation does not exist"), wantStatus: http.StatusInternalServerError, wantDetail: "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/subscription/1", nil)
+
+			h.handleError(c, "request failed", tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var problem problemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+			if problem.Code != errorCodes[tt.wantStatus] {
+				t.Errorf("code = %q, want %q", problem.Code, errorCodes[tt.wantStatus])
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fields := []models.FieldError{{Field: "price", Message: "price must be positive"}}
+
+	tests := []struct {
+		name            string
+		accept          string
+		status          int
+		wantContentType string
+		wantLegacy      bool
+		wantCode        string
+	}{
+		{name: "no Accept header", status: http.StatusUnprocessableEntity, wantContentType: problemContentType, wantCode: codeValidationFailed},
+		{name: "any type", accept: "*/*", status: http.StatusNotFound, wantContentType: problemContentType, wantCode: codeNotFound},
+		{name: "problem+json", accept: problemContentType, status: http.StatusConflict, wantContentType: problemContentType, wantCode: codeConflict},
+		{name: "legacy JSON client", accept: "application/json", status: http.StatusBadRequest, wantContentType: gin.MIMEJSON, wantLegacy: true, wantCode: codeBadRequest},
+		{name: "status without a code", accept: problemContentType, status: http.StatusTeapot, wantContentType: problemContentType, wantCode: codeInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/subscription/", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+			c.Set(requestIDKey, "req-1")
+
+			writeError(c, tt.status, "request failed", fields)
+
+			if !c.IsAborted() {
+				t.Error("writeError() did not abort the request")
+			}
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType && got != tt.wantContentType+"; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			if tt.wantLegacy {
+				var legacy errorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil {
+					t.Fatalf("response is not JSON: %v", err)
+				}
+				if want := (errorResponse{Error: "request failed", Code: tt.wantCode}); legacy != want {
+					t.Errorf("legacy response = %+v, want %+v", legacy, want)
+				}
+				return
+			}
+
+			var problem problemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			want := problemDetails{
+				Type:      problemTypeBase + tt.wantCode,
+				Title:     http.StatusText(tt.status),
+				Status:    tt.status,
+				Detail:    "request failed",
+				Instance:  "/subscription/",
+				Code:      tt.wantCode,
+				RequestID: "req-1",
+				Errors:    fields,
+			}
+			if !reflect.DeepEqual(problem, want) {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+		})
+	}
+}
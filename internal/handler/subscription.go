@@ -1,9 +1,15 @@
 package handler
 
 import (
-	"errors"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BountyM/effectiveMobileTestTask/internal/models"
@@ -18,62 +24,166 @@ type reqCreate struct {
 	UserID      uuid.UUID `json:"user_id"`
 	StartDate   string    `json:"start_date"`
 	EndDate     string    `json:"end_date,omitempty"`
+	// Цена списывается каждые billing_interval_count единиц billing_interval
+	// (по умолчанию — ежемесячно), первое списание — в день billing_anchor_day
+	// месяца начала (по умолчанию — первого числа)
+	BillingInterval      string `json:"billing_interval,omitempty" enums:"day,week,month,year"`
+	BillingIntervalCount int    `json:"billing_interval_count,omitempty" minimum:"1"`
+	BillingAnchorDay     int    `json:"billing_anchor_day,omitempty" minimum:"1" maximum:"31"`
+	// Пробный период задаётся последним бесплатным месяцем trial_end (MM-YYYY)
+	// или длительностью trial_months от месяца начала, но не обоими сразу
+	TrialEnd    string         `json:"trial_end,omitempty"`
+	TrialMonths int            `json:"trial_months,omitempty" minimum:"1"`
+	Promotions  []reqPromotion `json:"promotions,omitempty"`
+}
+
+// PromotionRequest model
+// Промо-период в месяцах start_month..end_month (MM-YYYY, включительно)
+type reqPromotion struct {
+	StartMonth string `json:"start_month"`
+	EndMonth   string `json:"end_month"`
+	Price      int64  `json:"price" minimum:"0"`
 }
 
 func reqToSubscription(r reqCreate) (models.Subscription, error) {
 	start, err := time.Parse("01-2006", r.StartDate)
 	if err != nil {
-		return models.Subscription{}, errors.New("invalid start_date format, expected MM-YYYY")
+		return models.Subscription{}, models.NewValidationError("start_date", "invalid start_date format, expected MM-YYYY")
 	}
 
 	var end *time.Time
 	if r.EndDate != "" {
 		parsedEnd, err := time.Parse("01-2006", r.EndDate)
 		if err != nil {
-			return models.Subscription{}, errors.New("invalid end_date format, expected MM-YYYY")
+			return models.Subscription{}, models.NewValidationError("end_date", "invalid end_date format, expected MM-YYYY")
 		}
 		// Дополнительная проверка: end_date должна быть после start_date
 		if parsedEnd.Before(start) {
-			return models.Subscription{}, errors.New("end_date must be after start_date")
+			return models.Subscription{}, models.NewValidationError("end_date", "end_date must be after start_date")
 		}
 		end = &parsedEnd
 	}
 
-	return models.Subscription{
-		ServiceName: r.ServiceName,
-		Price:       r.Price,
-		UserID:      r.UserID,
-		StartDate:   start,
-		EndDate:     end,
-	}, nil
+	sub := models.Subscription{
+		ServiceName:          r.ServiceName,
+		Price:                r.Price,
+		UserID:               r.UserID,
+		StartDate:            start,
+		EndDate:              end,
+		BillingInterval:      cmp.Or(r.BillingInterval, models.BillingMonth),
+		BillingIntervalCount: cmp.Or(r.BillingIntervalCount, 1),
+		BillingAnchorDay:     cmp.Or(r.BillingAnchorDay, 1),
+	}
+	sub.MonthlyPrice = sub.MonthlyEquivalent()
+
+	switch {
+	case r.TrialMonths > 0:
+		trialEnd := start.AddDate(0, r.TrialMonths-1, 0)
+		sub.TrialEnd = &trialEnd
+	case r.TrialEnd != "":
+		trialEnd, err := time.Parse("01-2006", r.TrialEnd)
+		if err != nil {
+			return models.Subscription{}, models.NewValidationError("trial_end", "invalid trial_end format, expected MM-YYYY")
+		}
+		if trialEnd.Before(start) {
+			return models.Subscription{}, models.NewValidationError("trial_end", "trial_end must not be before start_date")
+		}
+		sub.TrialEnd = &trialEnd
+	}
+
+	if sub.Promotions, err = reqToPromotions(r.Promotions); err != nil {
+		return models.Subscription{}, err
+	}
+	return sub, nil
+}
+
+// reqToPromotions разбирает промо-периоды и проверяет, что они не пересекаются
+func reqToPromotions(r []reqPromotion) ([]models.Promotion, error) {
+	promotions := make([]models.Promotion, 0, len(r))
+	for i, p := range r {
+		field := fmt.Sprintf("promotions[%d]", i)
+		start, err := time.Parse("01-2006", p.StartMonth)
+		if err != nil {
+			return nil, models.NewValidationError(field+".start_month", "invalid start_month format, expected MM-YYYY")
+		}
+		end, err := time.Parse("01-2006", p.EndMonth)
+		if err != nil {
+			return nil, models.NewValidationError(field+".end_month", "invalid end_month format, expected MM-YYYY")
+		}
+		if end.Before(start) {
+			return nil, models.NewValidationError(field+".end_month", "end_month must not be before start_month")
+		}
+		promotions = append(promotions, models.Promotion{StartMonth: start, EndMonth: end, Price: p.Price})
+	}
+
+	slices.SortFunc(promotions, func(a, b models.Promotion) int {
+		return a.StartMonth.Compare(b.StartMonth)
+	})
+	for i := 1; i < len(promotions); i++ {
+		if !promotions[i].StartMonth.After(promotions[i-1].EndMonth) {
+			return nil, models.NewValidationError("promotions", "promotions must not overlap")
+		}
+	}
+	return promotions, nil
 }
 
 // validateCreate проверяет обязательные поля
 func validateCreate(r reqCreate) error {
+	var verr models.ValidationError
 	if r.ServiceName == "" {
-		return errors.New("service_name is required")
+		verr.Add("service_name", "service_name is required")
 	}
 	if r.Price <= 0 {
-		return errors.New("price must be positive")
+		verr.Add("price", "price must be positive")
 	}
 	if r.UserID == uuid.Nil {
-		return errors.New("user_id is required")
+		verr.Add("user_id", "user_id is required")
 	}
 	if r.StartDate == "" {
-		return errors.New("start_date is required")
+		verr.Add("start_date", "start_date is required")
 	}
-	return nil
+	if _, ok := models.BillingUnitsPerMonth[r.BillingInterval]; r.BillingInterval != "" && !ok {
+		verr.Add("billing_interval", "billing_interval must be one of day, week, month, year")
+	}
+	if r.BillingIntervalCount < 0 {
+		verr.Add("billing_interval_count", "billing_interval_count must be positive")
+	}
+	if r.BillingAnchorDay < 0 || r.BillingAnchorDay > 31 {
+		verr.Add("billing_anchor_day", "billing_anchor_day must be between 1 and 31")
+	}
+	if r.TrialMonths < 0 {
+		verr.Add("trial_months", "trial_months must be positive")
+	}
+	if r.TrialMonths > 0 && r.TrialEnd != "" {
+		verr.Add("trial_months", "trial_months and trial_end are mutually exclusive")
+	}
+	for i, p := range r.Promotions {
+		if p.Price < 0 {
+			verr.Add(fmt.Sprintf("promotions[%d].price", i), "price must not be negative")
+		}
+	}
+	return verr.OrNil()
 }
 
 // @Summary Создать подписку
-// @Description Создаёт новую подписку для пользователя
+// @Description Создаёт новую подписку для пользователя. Пробный период задаётся trial_end или trial_months, промо-цены — promotions; цена price действует после них. С заголовком Idempotency-Key повторный запрос с тем же ключом и телом возвращает исходный ответ (с заголовком Idempotent-Replayed: true) вместо создания дубликата; тот же ключ с другим телом отклоняется с 422.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Ключ идемпотентности запроса"
 // @Param request body reqCreate true "Данные подписки"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
 // @Success 200 {object} object{res=string,uuid=string} "Успешное создание, возвращает ID подписки"
-// @Failure 400 {object} object{error=string} "Некорректные данные: invalid input body"
-// @Failure 500 {object} object{error=string} "Внутренняя ошибка сервера: internal error"
+// @Failure 400 {object} problemDetails "Некорректные данные: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 409 {object} problemDetails "Запрос с этим Idempotency-Key ещё выполняется, см. Retry-After"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку или Idempotency-Key использован с другим телом"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscription [post]
 func (h *Handler) createSubscription(c *gin.Context) {
 	logger := h.getRequestLogger(c)
@@ -87,22 +197,19 @@ func (h *Handler) createSubscription(c *gin.Context) {
 
 	// Валидация обязательных полей
 	if err := validateCreate(r); err != nil {
-		logger.Warn("validation failed", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "validation failed", err)
 		return
 	}
 
 	subscription, err := reqToSubscription(r)
 	if err != nil {
-		logger.Warn("invalid date format or logic", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "invalid date format or logic", err)
 		return
 	}
 
-	id, err := h.services.Create(subscription)
+	id, err := h.services.Create(c.Request.Context(), subscription)
 	if err != nil {
-		logger.Error("failed to create subscription", "error", err)
-		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		h.handleError(c, "failed to create subscription", err)
 		return
 	}
 
@@ -112,23 +219,120 @@ func (h *Handler) createSubscription(c *gin.Context) {
 	})
 }
 
+// @Summary Получить подписку
+// @Description Возвращает подписку по её ID. До появления этого маршрута путь /subscription/{user_id} возвращал подписки пользователя; теперь они доступны по GET /users/{user_id}/subscriptions. Ответ содержит заголовок ETag из версии подписки и хеша её представления: значения на текущую дату (state, in_trial, price, monthly_price) меняют ETag и без изменения подписки. При совпадении If-None-Match возвращается 304; для If-Match значима только версия.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка"
+// @Success 304 "Подписка не изменилась"
+// @Failure 400 {object} problemDetails "Некорректный ID подписки"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id} [get]
+func (h *Handler) getSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	subscription, err := h.services.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, "failed to get subscription", err)
+		return
+	}
+
+	if notModified(c, subscriptionETag(subscription)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
+
 // @Summary Получить подписки пользователя
 // @Description Возвращает список подписок пользователя с пагинацией. Если page или limit не указаны, используются значения по умолчанию: page=1, limit=10.
 // @Tags subscriptions
 // @Produce json
 // @Param user_id path string true "ID пользователя" format:"uuid"
-// @Param page path int false "Номер страницы" minimum:"1" default:"1"
-// @Param limit path int false "Количество записей на страницу" minimum:"1" maximum:"100" default:"10"
+// @Param page query int false "Номер страницы" minimum:"1" default:"1"
+// @Param limit query int false "Количество записей на страницу" minimum:"1" maximum:"100" default:"10"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
 // @Success 200 {object} object{res=string,subscriptions=[]models.Subscription} "Список подписок с пагинацией"
-// @Failure 400 {object} object{error=string} "Некорректный ID пользователя: invalid input body"
-// @Failure 500 {object} object{error=string} "Внутренняя ошибка сервера: internal error"
-// @Router /subscription/{user_id}/{page}/{limit} [get]
-// @Router /subscription/{user_id} [get]
+// @Success 304 "Список не изменился"
+// @Failure 400 {object} problemDetails "Некорректный ID пользователя: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /users/{user_id}/subscriptions [get]
 func (h *Handler) getSubscriptions(c *gin.Context) {
+	h.userSubscriptions(c, c.Param("user_id"), c.Query("page"), c.Query("limit"))
+}
+
+// legacyDeprecation — значение заголовка Deprecation (RFC 9745) для старых маршрутов:
+// момент, с которого они устарели
+const legacyDeprecation = "@1792108800"
+
+// @Summary Получить подписки пользователя (устаревший маршрут)
+// @Description Устаревший маршрут, оставлен для совместимости: то же, что GET /users/{user_id}/subscriptions?page={page}&limit={limit}. Ответ содержит заголовки Deprecation и Link на новый маршрут.
+// @Description Прежний маршрут GET /subscription/{user_id} без page и limit не сохранён: этот путь теперь возвращает подписку по её ID, для списка подписок пользователя используйте GET /users/{user_id}/subscriptions.
+// @Tags subscriptions
+// @Produce json
+// @Param user_id path string true "ID пользователя" format:"uuid"
+// @Param page path int true "Номер страницы" minimum:"1"
+// @Param limit path int true "Количество записей на страницу" minimum:"1" maximum:"100"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscriptions=[]models.Subscription} "Список подписок с пагинацией"
+// @Failure 400 {object} problemDetails "Некорректный ID пользователя: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Deprecated
+// @Router /subscription/{user_id}/{page}/{limit} [get]
+func (h *Handler) getSubscriptionsLegacy(c *gin.Context) {
+	// Параметр называется id: gin требует одного имени параметра у маршрутов /subscription/:id
+	userID, page, limit := c.Param("id"), c.Param("page"), c.Param("limit")
+	successor := url.URL{
+		Path:     "/users/" + url.PathEscape(userID) + "/subscriptions",
+		RawQuery: url.Values{"page": {page}, "limit": {limit}}.Encode(),
+	}
+	c.Header("Deprecation", legacyDeprecation)
+	c.Header("Link", "<"+successor.String()+`>; rel="successor-version"`)
+	h.userSubscriptions(c, userID, page, limit)
+}
+
+// userSubscriptions отдаёт страницу подписок пользователя; page и limit
+// вне допустимых значений заменяются значениями по умолчанию
+func (h *Handler) userSubscriptions(c *gin.Context, userIDStr, pageStr, limitStr string) {
 	logger := h.getRequestLogger(c)
 
 	// Проверяем обязательный параметр user_id
-	userIDStr := c.Param("user_id")
 	if userIDStr == "" {
 		newErrorResponse(c, http.StatusBadRequest, "user_id is required")
 		return
@@ -144,12 +348,12 @@ func (h *Handler) getSubscriptions(c *gin.Context) {
 	page := 1
 	limit := 10
 
-	if pageStr := c.Param("page"); pageStr != "" {
+	if pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
 			page = p
 		}
 	}
-	if limitStr := c.Param("limit"); limitStr != "" {
+	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
 			limit = l
 		}
@@ -161,27 +365,37 @@ func (h *Handler) getSubscriptions(c *gin.Context) {
 		Limit:  limit,
 	}
 
-	subscriptions, err := h.services.Get(params)
+	subscriptions, err := h.services.Get(c.Request.Context(), params)
 	if err != nil {
-		logger.Error("failed to get subscriptions", "error", err)
-		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		h.handleError(c, "failed to get subscriptions", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	jsonWithETag(c, gin.H{
 		"res":           "ok",
 		"subscriptions": subscriptions,
 	})
 }
 
 // @Summary Удалить подписку
-// @Description Удаляет подписку по её ID
+// @Description Удаляет подписку по её ID. При переданном If-Match подписка удаляется, только если её версия совпадает с ETag.
 // @Tags subscriptions
 // @Produce json
 // @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки; обязателен в строгом режиме"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
 // @Success 200 {object} object{res=string} "Успешное удаление"
-// @Failure 400 {object} object{error=string} "Некорректный ID подписки: invalid input body"
-// @Failure 500 {object} object{error=string} "Внутренняя ошибка сервера: internal error"
+// @Failure 400 {object} problemDetails "Некорректный ID подписки: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscription/{id} [delete]
 func (h *Handler) deleteSubscription(c *gin.Context) {
 	logger := h.getRequestLogger(c)
@@ -194,9 +408,13 @@ func (h *Handler) deleteSubscription(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.Delete(id); err != nil {
-		logger.Error("failed to delete subscription", "error", err)
-		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	if err := h.services.Delete(c.Request.Context(), id, ifVersions); err != nil {
+		h.handleError(c, "failed to delete subscription", err)
 		return
 	}
 
@@ -204,15 +422,29 @@ func (h *Handler) deleteSubscription(c *gin.Context) {
 }
 
 // @Summary Обновить подписку
-// @Description Обновляет данные подписки по ID. Принимает JSON с данными подписки.
+// @Description Обновляет данные подписки по ID. Принимает JSON с данными подписки. При переданном If-Match подписка обновляется, только если её версия совпадает с ETag; новый ETag возвращается в ответе.
+// @Description Новая цена действует с текущего месяца (или с начала подписки, если она ещё не началась) и не меняет стоимость прошлых месяцев; изменить цену с другого месяца можно через POST /subscription/{id}/prices.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки; обязателен в строгом режиме"
 // @Param request body reqCreate true "Обновляемые данные подписки"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
 // @Success 200 {object} object{res=string} "Успешное обновление"
-// @Failure 400 {object} object{error=string} "Некорректные данные: invalid input body"
-// @Failure 500 {object} object{error=string} "Внутренняя ошибка сервера: internal error"
+// @Failure 400 {object} problemDetails "Некорректные данные: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Изменение end_date отменённой подписки"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscription/{id} [put]
 func (h *Handler) updateSubscription(c *gin.Context) {
 	logger := h.getRequestLogger(c)
@@ -225,6 +457,11 @@ func (h *Handler) updateSubscription(c *gin.Context) {
 		return
 	}
 
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
 	var r reqCreate
 	if err := c.BindJSON(&r); err != nil {
 		logger.Warn("invalid JSON body", "error", err)
@@ -234,28 +471,28 @@ func (h *Handler) updateSubscription(c *gin.Context) {
 
 	// При обновлении можно разрешить частичное обновление, но для простоты проверим обязательные поля
 	if err := validateCreate(r); err != nil {
-		logger.Warn("validation failed", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "validation failed", err)
 		return
 	}
 
 	subscription, err := reqToSubscription(r)
 	if err != nil {
-		logger.Warn("invalid date format or logic", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "invalid date format or logic", err)
 		return
 	}
 
-	if err := h.services.Update(id, subscription); err != nil {
-		logger.Error("failed to update subscription", "error", err)
-		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+	updated, err := h.services.Update(c.Request.Context(), id, subscription, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to update subscription", err)
 		return
 	}
 
+	c.Header("ETag", subscriptionETag(updated))
 	c.JSON(http.StatusOK, gin.H{"res": "ok"})
 }
 
 // CostRequest model
+// Период задаётся месяцами в формате MM-YYYY, оба месяца входят в период.
 type reqCost struct {
 	UserID      *uuid.UUID `json:"user_id"`
 	ServiceName string     `json:"service_name"`
@@ -268,7 +505,7 @@ func reqToSubscriptionParams(r reqCost) (params models.SubscriptionParams, err e
 	if r.StartDate != "" {
 		start, err = time.Parse("01-2006", r.StartDate)
 		if err != nil {
-			return models.SubscriptionParams{}, errors.New("invalid start_date format, expected MM-YYYY")
+			return models.SubscriptionParams{}, models.NewValidationError("start_date", "invalid start_date format, expected MM-YYYY")
 		}
 		params.StartDate = start
 	}
@@ -277,7 +514,7 @@ func reqToSubscriptionParams(r reqCost) (params models.SubscriptionParams, err e
 	if r.EndDate != "" {
 		end, err = time.Parse("01-2006", r.EndDate)
 		if err != nil {
-			return models.SubscriptionParams{}, errors.New("invalid end_date format, expected MM-YYYY")
+			return models.SubscriptionParams{}, models.NewValidationError("end_date", "invalid end_date format, expected MM-YYYY")
 		}
 		params.EndDate = end
 	}
@@ -288,22 +525,37 @@ func reqToSubscriptionParams(r reqCost) (params models.SubscriptionParams, err e
 }
 
 func validateCost(params models.SubscriptionParams) error {
-
-	if !params.StartDate.IsZero() && !params.EndDate.IsZero() && params.EndDate.Before(params.StartDate) {
-		return errors.New("end_date must be after start_date")
+	// Без границ периода стоимость бессрочных подписок не определена
+	var verr models.ValidationError
+	if params.StartDate.IsZero() {
+		verr.Add("start_date", "start_date is required")
 	}
-	return nil
+	if params.EndDate.IsZero() {
+		verr.Add("end_date", "end_date is required")
+	} else if params.EndDate.Before(params.StartDate) {
+		verr.Add("end_date", "end_date must be after start_date")
+	}
+	return verr.OrNil()
 }
 
 // @Summary Рассчитать стоимость подписок
-// @Description Рассчитывает общую стоимость подписок пользователя за указанный период
+// @Description Рассчитывает общую стоимость подписок за указанный период (start_date и end_date включительно) как сумму списаний, даты которых попадают в период. Даты списаний определяются интервалом оплаты подписки: ежемесячная подписка списывается каждый месяц, годовая — раз в год, в день billing_anchor_day. Подписки без даты окончания учитываются до конца периода. В пробный период (до trial_end включительно) списаний нет, расписание списаний начинается с месяца после него; в промо-периоды списывается промо-цена. Списания в месяцы приостановки подписки не учитываются.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param request body reqCost true "Параметры расчёта стоимости"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
 // @Success 200 {object} object{res=string,cost=number} "Успешный расчёт, возвращает стоимость"
-// @Failure 400 {object} object{error=string} "Некорректные данные: invalid input body"
-// @Failure 500 {object} object{error=string} "Внутренняя ошибка сервера: internal error"
+// @Failure 400 {object} problemDetails "Некорректные данные: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Router /subscription/cost [post]
 func (h *Handler) getCost(c *gin.Context) {
 	logger := h.getRequestLogger(c)
@@ -317,20 +569,17 @@ func (h *Handler) getCost(c *gin.Context) {
 
 	params, err := reqToSubscriptionParams(r)
 	if err != nil {
-		logger.Warn("invalid date format", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "invalid date format", err)
 		return
 	}
 	if err := validateCost(params); err != nil {
-		logger.Warn("validation failed", "error", err)
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		h.handleError(c, "validation failed", err)
 		return
 	}
 
-	cost, err := h.services.GetCost(params)
+	cost, err := h.services.GetCost(c.Request.Context(), params)
 	if err != nil {
-		logger.Error("failed to calculate cost", "error", err)
-		newErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		h.handleError(c, "failed to calculate cost", err)
 		return
 	}
 
@@ -339,3 +588,349 @@ func (h *Handler) getCost(c *gin.Context) {
 		"cost": cost,
 	})
 }
+
+// CostBreakdownRequest model
+// Те же фильтры, что и для расчёта стоимости, плюс необязательная группировка.
+type reqCostBreakdown struct {
+	reqCost
+	// Допустимые значения: service_name, user_id
+	GroupBy []string `json:"group_by"`
+}
+
+func validateGroupBy(groupBy []string) error {
+	seen := make(map[string]bool, len(groupBy))
+	for _, group := range groupBy {
+		if group != models.CostGroupServiceName && group != models.CostGroupUserID {
+			return models.NewValidationError("group_by", "group_by must contain only service_name or user_id")
+		}
+		if seen[group] {
+			return models.NewValidationError("group_by", "group_by must not contain duplicates")
+		}
+		seen[group] = true
+	}
+	return nil
+}
+
+// @Summary Помесячная разбивка стоимости подписок
+// @Description Возвращает сумму списаний по подпискам за каждый календарный месяц периода (start_date и end_date включительно) и число подписок, списанных в месяце; месяцы без списаний не возвращаются. Фильтры совпадают с расчётом стоимости. При указании group_by месяцы дополнительно разбиваются по service_name и/или user_id.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param request body reqCostBreakdown true "Параметры разбивки стоимости"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,buckets=[]models.CostBucket} "Помесячная разбивка стоимости"
+// @Failure 400 {object} problemDetails "Некорректные данные: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/cost/breakdown [post]
+func (h *Handler) getCostBreakdown(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	var r reqCostBreakdown
+	if err := c.BindJSON(&r); err != nil {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := reqToSubscriptionParams(r.reqCost)
+	if err != nil {
+		h.handleError(c, "invalid date format", err)
+		return
+	}
+	if err := validateCost(params); err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+	if err := validateGroupBy(r.GroupBy); err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+	params.GroupBy = r.GroupBy
+
+	buckets, err := h.services.GetCostBreakdown(c.Request.Context(), params)
+	if err != nil {
+		h.handleError(c, "failed to calculate cost breakdown", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"res":     "ok",
+		"buckets": buckets,
+	})
+}
+
+// ListRequest model
+// Даты задаются месяцами в формате MM-YYYY, границы диапазонов включительно.
+type reqList struct {
+	UserID            string `form:"user_id"`
+	ServiceName       string `form:"service_name"`        // точное совпадение
+	ServiceNamePrefix string `form:"service_name_prefix"` // совпадение по префиксу
+	PriceMin          *int64 `form:"price_min"`
+	PriceMax          *int64 `form:"price_max"`
+	ActiveOn          string `form:"active_on"` // подписка активна в этом месяце
+	StartFrom         string `form:"start_from"`
+	StartTo           string `form:"start_to"`
+	EndFrom           string `form:"end_from"`
+	EndTo             string `form:"end_to"`
+	HasEndDate        *bool  `form:"has_end_date"`
+	InTrial           *bool  `form:"in_trial"` // подписка началась и пробный период идёт в текущем месяце
+	// Первое платное списание после пробного периода — не позже чем через столько дней
+	TrialEndingWithinDays *int `form:"trial_ending_within_days" minimum:"0"`
+	// Состояние подписки в текущем месяце
+	State string `form:"state" enums:"scheduled,active,paused,ended"`
+	// Ключи через запятую с необязательным направлением: price:desc,start_date:asc.
+	// Допустимые ключи: service_name, price, user_id, start_date, end_date
+	Sort  string `form:"sort"`
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+	// Режим пагинации: offset (по умолчанию) или cursor.
+	// Непустой cursor включает режим cursor автоматически
+	Pagination string `form:"pagination"`
+	// Непрозрачный курсор next_cursor из предыдущего ответа
+	Cursor string `form:"cursor"`
+}
+
+// encodeCursor кодирует курсор в непрозрачную для клиента строку
+func encodeCursor(cursor models.Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor разбирает курсор, полученный от клиента
+func decodeCursor(value string) (*models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, models.NewValidationError("cursor", "invalid cursor")
+	}
+	var cursor models.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Values) != len(cursor.Sort) {
+		return nil, models.NewValidationError("cursor", "invalid cursor")
+	}
+	// Без параметра sort сортировка берётся из курсора, который клиент может подделать
+	for _, field := range cursor.Sort {
+		if !slices.Contains(sortKeys, field.Field) {
+			return nil, models.NewValidationError("cursor", "invalid cursor")
+		}
+	}
+	return &cursor, nil
+}
+
+// parseMonth разбирает необязательную дату в формате MM-YYYY
+func parseMonth(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	month, err := time.Parse("01-2006", value)
+	if err != nil {
+		return nil, models.NewValidationError(field, fmt.Sprintf("invalid %s format, expected MM-YYYY", field))
+	}
+	return &month, nil
+}
+
+// sortKeys — поля, по которым можно сортировать список подписок
+var sortKeys = []string{models.SortServiceName, models.SortPrice, models.SortUserID, models.SortStartDate, models.SortEndDate}
+
+// parseSort разбирает параметр сортировки вида "price:desc,start_date"
+func parseSort(value string) ([]models.SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []models.SortField
+	for _, part := range strings.Split(value, ",") {
+		name, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if !slices.Contains(sortKeys, name) {
+			return nil, models.NewValidationError("sort", fmt.Sprintf("unknown sort key %q", name))
+		}
+
+		field := models.SortField{Field: name}
+		switch strings.ToLower(direction) {
+		case "", "asc":
+		case "desc":
+			field.Desc = true
+		default:
+			return nil, models.NewValidationError("sort", fmt.Sprintf("invalid sort direction %q, expected asc or desc", direction))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func reqListToSubscriptionParams(r reqList) (params models.SubscriptionParams, err error) {
+	if r.UserID != "" {
+		userID, err := uuid.Parse(r.UserID)
+		if err != nil {
+			return models.SubscriptionParams{}, models.NewValidationError("user_id", "invalid user_id format")
+		}
+		params.UserID = &userID
+	}
+
+	params.ServiceName = r.ServiceName
+	params.ServiceNamePrefix = r.ServiceNamePrefix
+	params.PriceMin = r.PriceMin
+	params.PriceMax = r.PriceMax
+	params.HasEndDate = r.HasEndDate
+	params.InTrial = r.InTrial
+	params.TrialEndingWithinDays = r.TrialEndingWithinDays
+
+	switch r.State {
+	case "", models.StateScheduled, models.StateActive, models.StatePaused, models.StateEnded:
+		params.State = r.State
+	default:
+		return models.SubscriptionParams{}, models.NewValidationError("state", "state must be one of scheduled, active, paused, ended")
+	}
+
+	dates := []struct {
+		value string
+		field string
+		dest  **time.Time
+	}{
+		{r.ActiveOn, "active_on", &params.ActiveOn},
+		{r.StartFrom, "start_from", &params.StartDateFrom},
+		{r.StartTo, "start_to", &params.StartDateTo},
+		{r.EndFrom, "end_from", &params.EndDateFrom},
+		{r.EndTo, "end_to", &params.EndDateTo},
+	}
+	for _, date := range dates {
+		if *date.dest, err = parseMonth(date.value, date.field); err != nil {
+			return models.SubscriptionParams{}, err
+		}
+	}
+
+	if params.Sort, err = parseSort(r.Sort); err != nil {
+		return models.SubscriptionParams{}, err
+	}
+
+	switch r.Pagination {
+	case "", "offset":
+		params.CursorPaging = r.Cursor != ""
+	case "cursor":
+		params.CursorPaging = true
+	default:
+		return models.SubscriptionParams{}, models.NewValidationError("pagination", "invalid pagination, expected offset or cursor")
+	}
+	if r.Cursor != "" {
+		if params.After, err = decodeCursor(r.Cursor); err != nil {
+			return models.SubscriptionParams{}, err
+		}
+		// Курсор действителен только для той сортировки, с которой он получен
+		if r.Sort == "" {
+			params.Sort = params.After.Sort
+		} else if !slices.Equal(params.Sort, params.After.Sort) {
+			return models.SubscriptionParams{}, models.NewValidationError("cursor", "cursor does not match sort")
+		}
+	}
+
+	// Значения по умолчанию, как и для списка подписок пользователя
+	params.Page = 1
+	params.Limit = 10
+	if r.Page > 0 {
+		params.Page = r.Page
+	}
+	if r.Limit > 0 && r.Limit <= 100 {
+		params.Limit = r.Limit
+	}
+	return params, nil
+}
+
+func validateList(params models.SubscriptionParams) error {
+	var verr models.ValidationError
+	if params.PriceMin != nil && params.PriceMax != nil && *params.PriceMax < *params.PriceMin {
+		verr.Add("price_max", "price_max must not be less than price_min")
+	}
+	if params.StartDateFrom != nil && params.StartDateTo != nil && params.StartDateTo.Before(*params.StartDateFrom) {
+		verr.Add("start_to", "start_to must not be before start_from")
+	}
+	if params.EndDateFrom != nil && params.EndDateTo != nil && params.EndDateTo.Before(*params.EndDateFrom) {
+		verr.Add("end_to", "end_to must not be before end_from")
+	}
+	if params.TrialEndingWithinDays != nil && *params.TrialEndingWithinDays < 0 {
+		verr.Add("trial_ending_within_days", "trial_ending_within_days must not be negative")
+	}
+	return verr.OrNil()
+}
+
+// @Summary Список подписок
+// @Description Возвращает подписки всех пользователей с фильтрацией, сортировкой и пагинацией. Если page или limit не указаны, используются значения по умолчанию: page=1, limit=10.
+// @Description В режиме offset ответ содержит total — общее число записей, подходящих под фильтры. В режиме cursor (pagination=cursor или непустой cursor) страницы читаются по ключу сортировки: ответ содержит next_cursor, который передаётся в cursor для получения следующей страницы; пустой next_cursor означает конец списка.
+// @Description in_trial отбирает начавшиеся подписки, пробный период которых идёт в текущем месяце (или все остальные при in_trial=false); trial_ending_within_days — подписки, первое платное списание которых наступит не позже чем через указанное число дней. state отбирает подписки по состоянию в текущем месяце.
+// @Tags subscriptions
+// @Produce json
+// @Param request query reqList false "Фильтры, сортировка и пагинация"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscriptions=[]models.Subscription,total=integer,page=integer,limit=integer,next_cursor=string} "Страница подписок"
+// @Success 304 "Страница не изменилась"
+// @Failure 400 {object} problemDetails "Некорректные параметры запроса"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 422 {object} problemDetails "Параметры не прошли проверку"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscriptions [get]
+func (h *Handler) listSubscriptions(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	var r reqList
+	if err := c.ShouldBindQuery(&r); err != nil {
+		logger.Warn("invalid query parameters", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := reqListToSubscriptionParams(r)
+	if err != nil {
+		h.handleError(c, "invalid query parameters", err)
+		return
+	}
+	if err := validateList(params); err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	list, err := h.services.List(c.Request.Context(), params)
+	if err != nil {
+		h.handleError(c, "failed to list subscriptions", err)
+		return
+	}
+
+	if params.CursorPaging {
+		var nextCursor string
+		if list.NextCursor != nil {
+			if nextCursor, err = encodeCursor(*list.NextCursor); err != nil {
+				h.handleError(c, "failed to encode cursor", err)
+				return
+			}
+		}
+		jsonWithETag(c, gin.H{
+			"res":           "ok",
+			"subscriptions": list.Subscriptions,
+			"limit":         params.Limit,
+			"next_cursor":   nextCursor,
+		})
+		return
+	}
+
+	jsonWithETag(c, gin.H{
+		"res":           "ok",
+		"subscriptions": list.Subscriptions,
+		"total":         list.Total,
+		"page":          params.Page,
+		"limit":         params.Limit,
+	})
+}
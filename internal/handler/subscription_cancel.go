@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxCancelNoteLength ограничивает длину комментария к отмене
+const maxCancelNoteLength = 1000
+
+// CancelRequest model
+// Причина отмены и момент, с которого она действует: effective — immediately
+// (последний оплачиваемый месяц — текущий) или end_of_period (по умолчанию:
+// подписка действует до конца оплаченного периода); вместо него можно задать
+// последний оплачиваемый месяц effective_month (MM-YYYY).
+type reqCancel struct {
+	Reason         string `json:"reason" enums:"too_expensive,not_using,switched_service,missing_features,technical_issues,other"`
+	Note           string `json:"note,omitempty" maxLength:"1000"`
+	Effective      string `json:"effective,omitempty" enums:"immediately,end_of_period"`
+	EffectiveMonth string `json:"effective_month,omitempty"`
+}
+
+// validateCancel проверяет запрос и преобразует его в отмену подписки
+func validateCancel(r reqCancel) (models.CancelRequest, error) {
+	var verr models.ValidationError
+	if !slices.Contains(models.CancelReasons, r.Reason) {
+		verr.Add("reason", "reason must be one of "+strings.Join(models.CancelReasons, ", "))
+	}
+	if len([]rune(r.Note)) > maxCancelNoteLength {
+		verr.Add("note", "note must not be longer than 1000 characters")
+	}
+
+	req := models.CancelRequest{Reason: r.Reason, Note: r.Note, When: cmp.Or(r.Effective, models.CancelEndOfPeriod)}
+	switch {
+	case r.EffectiveMonth != "" && r.Effective != "":
+		verr.Add("effective_month", "effective_month and effective are mutually exclusive")
+	case r.EffectiveMonth != "":
+		month, err := time.Parse("01-2006", r.EffectiveMonth)
+		if err != nil {
+			verr.Add("effective_month", "invalid effective_month format, expected MM-YYYY")
+		}
+		req.When, req.Month = models.CancelAtMonth, month
+	case req.When != models.CancelImmediately && req.When != models.CancelEndOfPeriod:
+		verr.Add("effective", "effective must be one of immediately, end_of_period")
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return models.CancelRequest{}, err
+	}
+	return req, nil
+}
+
+// @Summary Отменить подписку
+// @Description Отменяет подписку с указанием причины: последний оплачиваемый месяц записывается в end_date. При effective=immediately это текущий месяц, при effective=end_of_period (по умолчанию) — месяц перед следующим списанием, для ежемесячной подписки — тоже текущий; effective_month задаёт его явно. Месяц не может быть в прошлом, раньше начала или позже прежней даты окончания подписки.
+// @Description Подписку нельзя отменить повторно или после окончания (409). Ещё не начавшуюся подписку можно только удалить или отменить с effective_month, иначе возвращается 409. Отмену можно отозвать через POST /subscription/{id}/uncancel, пока не прошёл последний оплачиваемый месяц.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки"
+// @Param request body reqCancel true "Причина и момент отмены"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после отмены"
+// @Failure 400 {object} problemDetails "Некорректный ID или тело запроса"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Подписка уже отменена, закончилась или ещё не началась"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/cancel [post]
+func (h *Handler) cancelSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var r reqCancel
+	if err := c.BindJSON(&r); err != nil {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	req, err := validateCancel(r)
+	if err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	subscription, err := h.services.Cancel(c.Request.Context(), id, req, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to cancel subscription", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
+
+// @Summary Отозвать отмену подписки
+// @Description Отзывает действующую отмену подписки и восстанавливает прежнюю дату окончания. Доступно, пока не прошёл последний оплачиваемый месяц отмены; иначе, как и для неотменённой подписки, возвращается 409. Тело запроса не требуется.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после отзыва отмены"
+// @Failure 400 {object} problemDetails "Некорректный ID"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Подписка не отменена или отмена уже вступила в силу"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/uncancel [post]
+func (h *Handler) uncancelSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.services.Uncancel(c.Request.Context(), id, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to uncancel subscription", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
+
+// @Summary Отчёт по причинам отмены
+// @Description Возвращает число действующих (не отозванных) отмен подписок по причинам, последний оплачиваемый месяц которых попадает в период start_date..end_date включительно, и lost_monthly_spend — цену отменённых подписок в пересчёте на месяц. Фильтры совпадают с расчётом стоимости. Причины упорядочены по убыванию числа отмен.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param request body reqCost true "Период и фильтры отчёта"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,reasons=[]models.CancellationReasonStats} "Отмены по причинам"
+// @Failure 400 {object} problemDetails "Некорректные данные: invalid input body"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Запрошены подписки другого пользователя"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/cancellations/reasons [post]
+func (h *Handler) getCancellationReasons(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	var r reqCost
+	if err := c.BindJSON(&r); err != nil {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := reqToSubscriptionParams(r)
+	if err != nil {
+		h.handleError(c, "invalid date format", err)
+		return
+	}
+	if err := validateCost(params); err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	reasons, err := h.services.CancellationReasons(c.Request.Context(), params)
+	if err != nil {
+		h.handleError(c, "failed to build cancellation report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"res":     "ok",
+		"reasons": reasons,
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+)
+
+func TestValidateCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     reqCancel
+		want    models.CancelRequest
+		wantErr bool
+	}{
+		{
+			name: "end of period by default",
+			req:  reqCancel{Reason: models.CancelReasonNotUsing},
+			want: models.CancelRequest{Reason: models.CancelReasonNotUsing, When: models.CancelEndOfPeriod},
+		},
+		{
+			name: "immediately with note",
+			req:  reqCancel{Reason: models.CancelReasonOther, Note: "moving abroad", Effective: models.CancelImmediately},
+			want: models.CancelRequest{Reason: models.CancelReasonOther, Note: "moving abroad", When: models.CancelImmediately},
+		},
+		{
+			name: "explicit month",
+			req:  reqCancel{Reason: models.CancelReasonTooExpensive, EffectiveMonth: "09-2025"},
+			want: models.CancelRequest{
+				Reason: models.CancelReasonTooExpensive,
+				When:   models.CancelAtMonth,
+				Month:  time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "unknown reason", req: reqCancel{Reason: "bored"}, wantErr: true},
+		{name: "missing reason", req: reqCancel{}, wantErr: true},
+		{
+			name:    "note too long",
+			req:     reqCancel{Reason: models.CancelReasonOther, Note: strings.Repeat("я", maxCancelNoteLength+1)},
+			wantErr: true,
+		},
+		{
+			name: "note at the limit in runes",
+			req:  reqCancel{Reason: models.CancelReasonOther, Note: strings.Repeat("я", maxCancelNoteLength)},
+			want: models.CancelRequest{
+				Reason: models.CancelReasonOther,
+				Note:   strings.Repeat("я", maxCancelNoteLength),
+				When:   models.CancelEndOfPeriod,
+			},
+		},
+		{name: "unknown effective", req: reqCancel{Reason: models.CancelReasonOther, Effective: "tomorrow"}, wantErr: true},
+		{name: "invalid month", req: reqCancel{Reason: models.CancelReasonOther, EffectiveMonth: "2025-09"}, wantErr: true},
+		{
+			name:    "month and effective together",
+			req:     reqCancel{Reason: models.CancelReasonOther, Effective: models.CancelImmediately, EffectiveMonth: "09-2025"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateCancel(tt.req)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrValidation) {
+					t.Errorf("validateCancel(%+v) error = %v, want validation error", tt.req, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateCancel(%+v) unexpected error: %v", tt.req, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateCancel(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Типы содержимого, принимаемые PATCH
+const (
+	mimeMergePatch = "application/merge-patch+json" // RFC 7396
+	mimeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// subscriptionToReq представляет подписку в том же виде, в каком она принимается
+// при создании. К этому документу применяется патч; отсутствующая дата окончания
+// не попадает в документ, поэтому merge patch с "end_date": null её и сбрасывает.
+// Пробный период представлен полем trial_end; trial_months в документе не бывает.
+func subscriptionToReq(s models.Subscription) reqCreate {
+	r := reqCreate{
+		ServiceName:          s.ServiceName,
+		Price:                s.Price,
+		UserID:               s.UserID,
+		StartDate:            s.StartDate.Format("01-2006"),
+		BillingInterval:      s.BillingInterval,
+		BillingIntervalCount: s.BillingIntervalCount,
+		BillingAnchorDay:     s.BillingAnchorDay,
+	}
+	if s.EndDate != nil {
+		r.EndDate = s.EndDate.Format("01-2006")
+	}
+	if s.TrialEnd != nil {
+		r.TrialEnd = s.TrialEnd.Format("01-2006")
+	}
+	for _, p := range s.Promotions {
+		r.Promotions = append(r.Promotions, reqPromotion{
+			StartMonth: p.StartMonth.Format("01-2006"),
+			EndMonth:   p.EndMonth.Format("01-2006"),
+			Price:      p.Price,
+		})
+	}
+	return r
+}
+
+// applyPatch применяет к документу merge patch или JSON Patch в зависимости от типа содержимого
+func applyPatch(contentType string, doc, patch []byte) ([]byte, error) {
+	switch contentType {
+	case mimeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch document: %w", err)
+		}
+		patched, err := ops.Apply(doc)
+		if err != nil {
+			return nil, models.NewValidationError("patch", fmt.Sprintf("failed to apply JSON Patch: %v", err))
+		}
+		return patched, nil
+	default:
+		if !json.Valid(patch) {
+			return nil, fmt.Errorf("invalid merge patch document")
+		}
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, models.NewValidationError("patch", fmt.Sprintf("failed to apply merge patch: %v", err))
+		}
+		return patched, nil
+	}
+}
+
+// diffSubscription строит патч, затрагивающий только изменившиеся поля
+func diffSubscription(old, updated models.Subscription) models.SubscriptionPatch {
+	var patch models.SubscriptionPatch
+	if updated.ServiceName != old.ServiceName {
+		patch.ServiceName = &updated.ServiceName
+	}
+	if updated.Price != old.Price {
+		patch.Price = &updated.Price
+	}
+	if updated.UserID != old.UserID {
+		patch.UserID = &updated.UserID
+	}
+	if !updated.StartDate.Equal(old.StartDate) {
+		patch.StartDate = &updated.StartDate
+	}
+	switch {
+	case updated.EndDate == nil && old.EndDate != nil:
+		patch.ClearEndDate = true
+	case updated.EndDate != nil && (old.EndDate == nil || !updated.EndDate.Equal(*old.EndDate)):
+		patch.EndDate = updated.EndDate
+	}
+	if updated.BillingInterval != old.BillingInterval {
+		patch.BillingInterval = &updated.BillingInterval
+	}
+	if updated.BillingIntervalCount != old.BillingIntervalCount {
+		patch.BillingIntervalCount = &updated.BillingIntervalCount
+	}
+	if updated.BillingAnchorDay != old.BillingAnchorDay {
+		patch.BillingAnchorDay = &updated.BillingAnchorDay
+	}
+	switch {
+	case updated.TrialEnd == nil && old.TrialEnd != nil:
+		patch.ClearTrialEnd = true
+	case updated.TrialEnd != nil && (old.TrialEnd == nil || !updated.TrialEnd.Equal(*old.TrialEnd)):
+		patch.TrialEnd = updated.TrialEnd
+	}
+	if !slices.EqualFunc(updated.Promotions, old.Promotions, func(a, b models.Promotion) bool {
+		return a.StartMonth.Equal(b.StartMonth) && a.EndMonth.Equal(b.EndMonth) && a.Price == b.Price
+	}) {
+		patch.Promotions = &updated.Promotions
+	}
+	return patch
+}
+
+// @Summary Частично обновить подписку
+// @Description Изменяет только переданные поля подписки. Тело — JSON Merge Patch (RFC 7396, Content-Type: application/merge-patch+json или application/json) либо JSON Patch (RFC 6902, Content-Type: application/json-patch+json).
+// @Description Патч применяется к представлению подписки в формате запроса на создание (даты MM-YYYY); "end_date": null в merge patch сбрасывает дату окончания, отсутствие поля оставляет её без изменений. Результат проверяется так же, как при создании, включая порядок дат. Новая цена действует с текущего месяца, как при PUT. Пробный период в документе задан полем trial_end; чтобы задать его длительностью, патч должен одновременно сбросить trial_end и передать trial_months. Массив promotions заменяется целиком.
+// @Tags subscriptions
+// @Accept application/merge-patch+json,application/json-patch+json,json
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки; обязателен в строгом режиме"
+// @Param request body reqCreate true "Изменяемые поля подписки или массив операций JSON Patch"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после изменения"
+// @Failure 400 {object} problemDetails "Некорректный ID или документ патча"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Подписка изменена параллельно (запрос без If-Match) или изменяется end_date отменённой подписки"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 415 {object} problemDetails "Неподдерживаемый тип содержимого"
+// @Failure 422 {object} problemDetails "Результат патча не прошёл проверку"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id} [patch]
+func (h *Handler) patchSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != mimeMergePatch && contentType != mimeJSONPatch && contentType != gin.MIMEJSON {
+		logger.Warn("unsupported patch content type", "content_type", contentType)
+		newErrorResponse(c, http.StatusUnsupportedMediaType,
+			"content type must be "+mimeMergePatch+" or "+mimeJSONPatch)
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		logger.Warn("failed to read request body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	current, err := h.services.GetByID(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, "failed to get subscription", err)
+		return
+	}
+
+	doc, err := json.Marshal(subscriptionToReq(current))
+	if err != nil {
+		h.handleError(c, "failed to encode subscription", err)
+		return
+	}
+
+	patched, err := applyPatch(contentType, doc, body)
+	if err != nil {
+		// Патч, который нельзя применить к подписке, — ошибка проверки, испорченный документ — 400
+		if errors.Is(err, models.ErrValidation) {
+			h.handleError(c, "failed to apply patch", err)
+			return
+		}
+		logger.Warn("invalid patch document", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Результат патча проверяется по тем же правилам, что и запрос на создание
+	var r reqCreate
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&r); err != nil {
+		h.handleError(c, "invalid patch result", models.NewValidationError("patch", err.Error()))
+		return
+	}
+	if err := validateCreate(r); err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+	updated, err := reqToSubscription(r)
+	if err != nil {
+		h.handleError(c, "invalid date format or logic", err)
+		return
+	}
+
+	// Без If-Match патч всё равно применяется к прочитанной версии, чтобы не затереть
+	// изменения, сделанные между чтением и записью; клиент в этом случае получает 409
+	implicitVersion := ifVersions == nil
+	if implicitVersion {
+		ifVersions = []int64{current.Version}
+	}
+
+	subscription, err := h.services.Patch(c.Request.Context(), id, diffSubscription(current, updated), ifVersions)
+	if implicitVersion && errors.Is(err, models.ErrPreconditionFailed) {
+		err = fmt.Errorf("%w: %v", models.ErrConflict, err)
+	}
+	if err != nil {
+		h.handleError(c, "failed to patch subscription", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
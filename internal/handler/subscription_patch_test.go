@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestDiffSubscription(t *testing.T) {
+	jan := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	old := models.Subscription{
+		ServiceName:          "Netflix",
+		Price:                400,
+		UserID:               uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		StartDate:            jan,
+		EndDate:              &jun,
+		BillingInterval:      models.BillingMonth,
+		BillingIntervalCount: 1,
+		TrialEnd:             &mar,
+		Promotions:           []models.Promotion{{StartMonth: jan, EndMonth: mar, Price: 200}},
+	}
+
+	name := "Spotify"
+	price := int64(500)
+	interval := models.BillingYear
+	promotions := []models.Promotion{{StartMonth: jan, EndMonth: mar, Price: 100}}
+
+	tests := []struct {
+		name   string
+		update func(s *models.Subscription)
+		want   models.SubscriptionPatch
+	}{
+		{name: "nothing changed", update: func(*models.Subscription) {}},
+		{
+			name:   "same dates in another location",
+			update: func(s *models.Subscription) { s.StartDate = jan.In(time.FixedZone("MSK", 3*60*60)) },
+		},
+		{
+			name:   "scalar fields",
+			update: func(s *models.Subscription) { s.ServiceName, s.Price, s.BillingInterval = name, price, interval },
+			want:   models.SubscriptionPatch{ServiceName: &name, Price: &price, BillingInterval: &interval},
+		},
+		{
+			name:   "end date cleared",
+			update: func(s *models.Subscription) { s.EndDate = nil },
+			want:   models.SubscriptionPatch{ClearEndDate: true},
+		},
+		{
+			name:   "end date moved",
+			update: func(s *models.Subscription) { s.EndDate = &mar },
+			want:   models.SubscriptionPatch{EndDate: &mar},
+		},
+		{
+			name:   "trial end cleared",
+			update: func(s *models.Subscription) { s.TrialEnd = nil },
+			want:   models.SubscriptionPatch{ClearTrialEnd: true},
+		},
+		{
+			name:   "promotion price changed",
+			update: func(s *models.Subscription) { s.Promotions = promotions },
+			want:   models.SubscriptionPatch{Promotions: &promotions},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := old
+			tt.update(&updated)
+
+			got := diffSubscription(old, updated)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSubscription() = %+v, want %+v", got, tt.want)
+			}
+			if got.IsEmpty() != reflect.DeepEqual(tt.want, models.SubscriptionPatch{}) {
+				t.Errorf("diffSubscription().IsEmpty() = %v", got.IsEmpty())
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PauseRequest model
+// Месяцы приостановки в формате MM-YYYY. Без start_month подписка приостанавливается
+// с текущего месяца, без end_month — до возобновления.
+type reqPause struct {
+	StartMonth string `json:"start_month,omitempty"`
+	EndMonth   string `json:"end_month,omitempty"`
+}
+
+// ResumeRequest model
+// Месяц (MM-YYYY), с которого подписка снова оплачивается; по умолчанию — текущий
+type reqResume struct {
+	Month string `json:"month,omitempty"`
+}
+
+// validatePause преобразует запрос в приостановку
+func validatePause(r reqPause) (models.Pause, error) {
+	var pause models.Pause
+	start, err := parseMonth(r.StartMonth, "start_month")
+	if err != nil {
+		return models.Pause{}, err
+	}
+	if start != nil {
+		pause.StartMonth = *start
+	}
+	if pause.EndMonth, err = parseMonth(r.EndMonth, "end_month"); err != nil {
+		return models.Pause{}, err
+	}
+	return pause, nil
+}
+
+// @Summary Приостановить подписку
+// @Description Приостанавливает подписку на месяцы start_month..end_month включительно: эти месяцы не учитываются в стоимости и расходах, а списания за периоды длиннее месяца уменьшаются пропорционально приостановленным месяцам. Без start_month приостановка начинается с текущего месяца, без end_month длится до возобновления. Тело запроса можно не передавать.
+// @Description Приостановка не может начинаться раньше начала или позже окончания подписки и пересекаться с уже записанными приостановками (409).
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки"
+// @Param request body reqPause false "Месяцы приостановки"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после приостановки"
+// @Failure 400 {object} problemDetails "Некорректный ID или тело запроса"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Подписка уже приостановлена в этом периоде"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/pause [post]
+func (h *Handler) pauseSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	// Пустое тело означает приостановку с текущего месяца до возобновления
+	var r reqPause
+	if err := c.ShouldBindJSON(&r); err != nil && !errors.Is(err, io.EOF) {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	pause, err := validatePause(r)
+	if err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	subscription, err := h.services.Pause(c.Request.Context(), id, pause, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to pause subscription", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
+
+// @Summary Возобновить подписку
+// @Description Возобновляет подписку с месяца month (по умолчанию — с текущего): приостановка, в которую входит этот месяц, заканчивается предыдущим месяцем, а начинающаяся с него отменяется. Тело запроса можно не передавать.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки"
+// @Param request body reqResume false "Месяц возобновления"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после возобновления"
+// @Failure 400 {object} problemDetails "Некорректный ID или тело запроса"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 409 {object} problemDetails "Подписка не приостановлена в этом месяце"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/resume [post]
+func (h *Handler) resumeSubscription(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var r reqResume
+	if err := c.ShouldBindJSON(&r); err != nil && !errors.Is(err, io.EOF) {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	month, err := parseMonth(r.Month, "month")
+	if err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+	var resumeFrom time.Time
+	if month != nil {
+		resumeFrom = *month
+	}
+
+	subscription, err := h.services.Resume(c.Request.Context(), id, resumeFrom, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to resume subscription", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
+
+// @Summary Отменить запланированную приостановку
+// @Description Удаляет приостановку подписки, которая начинается с месяца start_month (MM-YYYY) и ещё не началась. Начавшуюся приостановку завершает POST /subscription/{id}/resume (409).
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param start_month path string true "Первый месяц приостановки (MM-YYYY)"
+// @Param If-Match header string false "ETag подписки"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после отмены приостановки"
+// @Failure 400 {object} problemDetails "Некорректный ID"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка или приостановка не найдена"
+// @Failure 409 {object} problemDetails "Приостановка уже началась"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 422 {object} problemDetails "Некорректный месяц"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/pause/{start_month} [delete]
+func (h *Handler) cancelPause(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	month, err := parseMonth(c.Param("start_month"), "start_month")
+	if err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	subscription, err := h.services.CancelPause(c.Request.Context(), id, *month, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to cancel pause", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+)
+
+func TestValidatePause(t *testing.T) {
+	march := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		req       reqPause
+		want      models.Pause
+		wantField string
+	}{
+		// Месяц начала по умолчанию подставляет сервис
+		{name: "empty body", req: reqPause{}, want: models.Pause{}},
+		{name: "open-ended", req: reqPause{StartMonth: "03-2026"}, want: models.Pause{StartMonth: march}},
+		{name: "bounded", req: reqPause{StartMonth: "03-2026", EndMonth: "05-2026"}, want: models.Pause{StartMonth: march, EndMonth: &may}},
+		{name: "until month from now", req: reqPause{EndMonth: "05-2026"}, want: models.Pause{EndMonth: &may}},
+		{name: "invalid start month", req: reqPause{StartMonth: "2026-03"}, wantField: "start_month"},
+		{name: "invalid end month", req: reqPause{StartMonth: "03-2026", EndMonth: "5-2026"}, wantField: "end_month"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validatePause(tt.req)
+			if tt.wantField != "" {
+				var verr *models.ValidationError
+				if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != tt.wantField {
+					t.Fatalf("validatePause(%+v) error = %v, want validation error on %s", tt.req, err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validatePause(%+v) unexpected error: %v", tt.req, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validatePause(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceChangeRequest model
+// Новая цена подписки и месяц (MM-YYYY), с которого она действует
+type reqPriceChange struct {
+	Price         int64  `json:"price"`
+	EffectiveFrom string `json:"effective_from"`
+}
+
+// validatePriceChange проверяет запрос и преобразует его в изменение цены
+func validatePriceChange(r reqPriceChange) (models.PriceChange, error) {
+	var verr models.ValidationError
+	if r.Price <= 0 {
+		verr.Add("price", "price must be positive")
+	}
+	from, err := time.Parse("01-2006", r.EffectiveFrom)
+	if err != nil {
+		verr.Add("effective_from", "invalid effective_from format, expected MM-YYYY")
+	}
+	if err := verr.OrNil(); err != nil {
+		return models.PriceChange{}, err
+	}
+	return models.PriceChange{EffectiveFrom: from, Price: r.Price}, nil
+}
+
+// @Summary Изменить цену подписки
+// @Description Записывает цену, действующую с месяца effective_from до следующего изменения. Месяц в прошлом исправляет историю цен и стоимость прошлых месяцев, месяц в будущем планирует изменение. Повторный запрос с тем же месяцем заменяет цену этого месяца.
+// @Description Месяц не может быть раньше начала подписки. Ответ содержит подписку с действующей ценой и историей цен.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "ID подписки" format:"uuid"
+// @Param If-Match header string false "ETag подписки"
+// @Param request body reqPriceChange true "Цена и месяц, с которого она действует"
+// @Param X-Tenant-ID header string false "Арендатор для роли platform_admin; учётные данные с арендатором могут передать только его"
+// @Success 200 {object} object{res=string,subscription=models.Subscription} "Подписка после изменения цены"
+// @Failure 400 {object} problemDetails "Некорректный ID или тело запроса"
+// @Failure 401 {object} problemDetails "Не переданы или неверны учётные данные"
+// @Failure 403 {object} problemDetails "Операция недоступна вызывающему"
+// @Failure 404 {object} problemDetails "Подписка не найдена"
+// @Failure 412 {object} problemDetails "Версия подписки не совпадает с If-Match"
+// @Failure 422 {object} problemDetails "Данные не прошли проверку"
+// @Failure 428 {object} problemDetails "Не передан If-Match в строгом режиме"
+// @Failure 429 {object} problemDetails "Превышен лимит запросов, см. Retry-After"
+// @Failure 500 {object} problemDetails "Внутренняя ошибка сервера: internal error"
+// @Failure 503 {object} problemDetails "База данных недоступна"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /subscription/{id}/prices [post]
+func (h *Handler) setSubscriptionPrice(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		logger.Warn("invalid subscription id format", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	ifVersions, ok := h.ifMatchVersions(c)
+	if !ok {
+		return
+	}
+
+	var r reqPriceChange
+	if err := c.BindJSON(&r); err != nil {
+		logger.Warn("invalid JSON body", "error", err)
+		newErrorResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	change, err := validatePriceChange(r)
+	if err != nil {
+		h.handleError(c, "validation failed", err)
+		return
+	}
+
+	subscription, err := h.services.SetPrice(c.Request.Context(), id, change, ifVersions)
+	if err != nil {
+		h.handleError(c, "failed to set subscription price", err)
+		return
+	}
+
+	c.Header("ETag", subscriptionETag(subscription))
+	c.JSON(http.StatusOK, gin.H{
+		"res":          "ok",
+		"subscription": subscription,
+	})
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+)
+
+func TestValidatePriceChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        reqPriceChange
+		want       models.PriceChange
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			req:  reqPriceChange{Price: 499, EffectiveFrom: "03-2026"},
+			want: models.PriceChange{EffectiveFrom: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Price: 499},
+		},
+		{name: "zero price", req: reqPriceChange{Price: 0, EffectiveFrom: "03-2026"}, wantFields: []string{"price"}},
+		{name: "negative price", req: reqPriceChange{Price: -1, EffectiveFrom: "03-2026"}, wantFields: []string{"price"}},
+		{name: "invalid month", req: reqPriceChange{Price: 499, EffectiveFrom: "2026-03"}, wantFields: []string{"effective_from"}},
+		{name: "missing month", req: reqPriceChange{Price: 499}, wantFields: []string{"effective_from"}},
+		{name: "all fields reported", req: reqPriceChange{}, wantFields: []string{"price", "effective_from"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validatePriceChange(tt.req)
+			if tt.wantFields != nil {
+				var verr *models.ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("validatePriceChange(%+v) error = %v, want validation error", tt.req, err)
+				}
+				fields := make([]string, 0, len(verr.Fields))
+				for _, field := range verr.Fields {
+					fields = append(fields, field.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Errorf("validatePriceChange(%+v) fields = %v, want %v", tt.req, fields, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validatePriceChange(%+v) unexpected error: %v", tt.req, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validatePriceChange(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
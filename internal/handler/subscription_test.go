@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []models.SortField
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: nil},
+		{name: "single key", in: "price", want: []models.SortField{{Field: models.SortPrice}}},
+		{
+			name: "several keys with directions",
+			in:   "price:desc, start_date:asc,service_name",
+			want: []models.SortField{
+				{Field: models.SortPrice, Desc: true},
+				{Field: models.SortStartDate},
+				{Field: models.SortServiceName},
+			},
+		},
+		{name: "direction is case-insensitive", in: "end_date:DESC", want: []models.SortField{{Field: models.SortEndDate, Desc: true}}},
+		{name: "unknown key", in: "created_at", wantErr: true},
+		{name: "unknown direction", in: "price:up", wantErr: true},
+		{name: "empty key", in: "price,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSort(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSort(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, models.ErrValidation) {
+				t.Errorf("parseSort(%q) error = %v, want ErrValidation", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	valid := models.Cursor{
+		Sort:   []models.SortField{{Field: models.SortPrice, Desc: true}, {Field: models.SortEndDate}},
+		Values: []string{"400", "infinity"},
+		ID:     uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+	}
+	encoded, err := encodeCursor(valid)
+	if err != nil {
+		t.Fatalf("encodeCursor() error = %v", err)
+	}
+	encodeJSON := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name    string
+		in      string
+		want    *models.Cursor
+		wantErr bool
+	}{
+		{name: "round trip", in: encoded, want: &valid},
+		{name: "id only", in: encodeJSON(`{"id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`), want: &models.Cursor{ID: valid.ID}},
+		{name: "not base64url", in: "???", wantErr: true},
+		{name: "padded base64", in: base64.URLEncoding.EncodeToString([]byte(`{"id": "60601fee-2bf1-4721-ae6f-7636e79a0cba"}`)), wantErr: true},
+		{name: "not JSON", in: encodeJSON("cursor"), wantErr: true},
+		{name: "invalid id", in: encodeJSON(`{"id":"42"}`), wantErr: true},
+		{name: "values do not match sort", in: encodeJSON(`{"s":[{"f":"price"}],"v":[],"id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`), wantErr: true},
+		{name: "unknown sort key", in: encodeJSON(`{"s":[{"f":"created_at"}],"v":["2026-04-01"],"id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, models.ErrValidation) {
+				t.Errorf("decodeCursor(%q) error = %v, want ErrValidation", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCursor(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostParams(t *testing.T) {
+	userID := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+
+	tests := []struct {
+		name    string
+		req     reqCost
+		want    models.SubscriptionParams
+		wantErr bool
+	}{
+		{
+			name: "period with filters",
+			req:  reqCost{UserID: &userID, ServiceName: "Netflix", StartDate: "01-2025", EndDate: "12-2025"},
+			want: models.SubscriptionParams{
+				UserID:      &userID,
+				ServiceName: "Netflix",
+				StartDate:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:     time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "single month",
+			req:  reqCost{StartDate: "03-2025", EndDate: "03-2025"},
+			want: models.SubscriptionParams{
+				StartDate: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "invalid start date", req: reqCost{StartDate: "2025-01", EndDate: "12-2025"}, wantErr: true},
+		{name: "invalid end date", req: reqCost{StartDate: "01-2025", EndDate: "13-2025"}, wantErr: true},
+		// Без границ периода стоимость бессрочных подписок не определена
+		{name: "no start date", req: reqCost{EndDate: "12-2025"}, wantErr: true},
+		{name: "no end date", req: reqCost{StartDate: "01-2025"}, wantErr: true},
+		{name: "end before start", req: reqCost{StartDate: "02-2025", EndDate: "01-2025"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reqToSubscriptionParams(tt.req)
+			if err == nil {
+				err = validateCost(got)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrValidation) {
+					t.Errorf("cost params %+v error = %v, want ErrValidation", tt.req, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cost params %+v unexpected error: %v", tt.req, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reqToSubscriptionParams(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy []string
+		wantErr bool
+	}{
+		{name: "no grouping"},
+		{name: "by service", groupBy: []string{models.CostGroupServiceName}},
+		{name: "by service and user", groupBy: []string{models.CostGroupServiceName, models.CostGroupUserID}},
+		{name: "by user and service", groupBy: []string{models.CostGroupUserID, models.CostGroupServiceName}},
+		{name: "unknown field", groupBy: []string{"tenant_id"}, wantErr: true},
+		{name: "duplicate field", groupBy: []string{models.CostGroupUserID, models.CostGroupUserID}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGroupBy(tt.groupBy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateGroupBy(%v) error = %v, wantErr %v", tt.groupBy, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, models.ErrValidation) {
+				t.Errorf("validateGroupBy(%v) error = %v, want ErrValidation", tt.groupBy, err)
+			}
+		})
+	}
+}
+
+func TestReqToPromotions(t *testing.T) {
+	month := func(m time.Month, year int) time.Time { return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		req       []reqPromotion
+		want      []models.Promotion
+		wantField string
+	}{
+		{name: "no promotions", want: []models.Promotion{}},
+		{
+			name: "sorted by start month",
+			req: []reqPromotion{
+				{StartMonth: "04-2026", EndMonth: "06-2026", Price: 299},
+				{StartMonth: "01-2026", EndMonth: "03-2026", Price: 0},
+			},
+			want: []models.Promotion{
+				{StartMonth: month(time.January, 2026), EndMonth: month(time.March, 2026), Price: 0},
+				{StartMonth: month(time.April, 2026), EndMonth: month(time.June, 2026), Price: 299},
+			},
+		},
+		{
+			name: "single month",
+			req:  []reqPromotion{{StartMonth: "02-2026", EndMonth: "02-2026", Price: 99}},
+			want: []models.Promotion{{StartMonth: month(time.February, 2026), EndMonth: month(time.February, 2026), Price: 99}},
+		},
+		{
+			name: "overlapping",
+			req: []reqPromotion{
+				{StartMonth: "01-2026", EndMonth: "03-2026"},
+				{StartMonth: "03-2026", EndMonth: "05-2026"},
+			},
+			wantField: "promotions",
+		},
+		{
+			name:      "invalid start month",
+			req:       []reqPromotion{{StartMonth: "01-2026", EndMonth: "02-2026"}, {StartMonth: "2026-03", EndMonth: "04-2026"}},
+			wantField: "promotions[1].start_month",
+		},
+		{
+			name:      "invalid end month",
+			req:       []reqPromotion{{StartMonth: "01-2026", EndMonth: "13-2026"}},
+			wantField: "promotions[0].end_month",
+		},
+		{
+			name:      "end before start",
+			req:       []reqPromotion{{StartMonth: "05-2026", EndMonth: "04-2026"}},
+			wantField: "promotions[0].end_month",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reqToPromotions(tt.req)
+			if tt.wantField != "" {
+				var verr *models.ValidationError
+				if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != tt.wantField {
+					t.Fatalf("reqToPromotions(%+v) error = %v, want validation error on %s", tt.req, err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reqToPromotions(%+v) unexpected error: %v", tt.req, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reqToPromotions(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReqToSubscriptionTrial(t *testing.T) {
+	base := reqCreate{ServiceName: "Netflix", Price: 499, UserID: uuid.New(), StartDate: "03-2026"}
+
+	tests := []struct {
+		name        string
+		trialEnd    string
+		trialMonths int
+		// want — последний месяц пробного периода (MM-YYYY), пусто — без пробного периода
+		want    string
+		wantErr bool
+	}{
+		{name: "no trial"},
+		{name: "one month", trialMonths: 1, want: "03-2026"},
+		{name: "across the year", trialMonths: 12, want: "02-2027"},
+		{name: "explicit last month", trialEnd: "05-2026", want: "05-2026"},
+		{name: "ends in start month", trialEnd: "03-2026", want: "03-2026"},
+		{name: "before start", trialEnd: "02-2026", wantErr: true},
+		{name: "invalid format", trialEnd: "2026-05", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			req.TrialEnd, req.TrialMonths = tt.trialEnd, tt.trialMonths
+
+			got, err := reqToSubscription(req)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrValidation) {
+					t.Errorf("reqToSubscription(%+v) error = %v, want validation error", req, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reqToSubscription(%+v) unexpected error: %v", req, err)
+			}
+			var trialEnd string
+			if got.TrialEnd != nil {
+				trialEnd = got.TrialEnd.Format("01-2006")
+			}
+			if trialEnd != tt.want {
+				t.Errorf("reqToSubscription(%+v) trial_end = %q, want %q", req, trialEnd, tt.want)
+			}
+		})
+	}
+}
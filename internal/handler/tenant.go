@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// tenantKey — ключ арендатора в контексте Gin
+const tenantKey = "tenant"
+
+// resolveTenant определяет арендатора запроса. Учётные данные, привязанные к арендатору
+// (claim tenant_id токена или арендатор API-ключа), не могут обратиться к другому через
+// заголовок. Остальные вызывающие работают с HTTP_DEFAULT_TENANT; выбрать арендатора
+// заголовком HTTP_TENANT_HEADER может только роль platform_admin или любой вызывающий
+// при отключённой аутентификации.
+func (h *Handler) resolveTenant(c *gin.Context) {
+	logger := h.getRequestLogger(c)
+
+	header := c.GetHeader(h.cfg.TenantHeader)
+	tenant := header
+	if principal, ok := getPrincipal(c); ok {
+		switch {
+		case principal.TenantID != "":
+			if header != "" && header != principal.TenantID {
+				logger.Warn("tenant header does not match credentials", "tenant", header, "credentials_tenant", principal.TenantID)
+				newErrorResponse(c, http.StatusForbidden, "credentials are not valid for the requested tenant")
+				return
+			}
+			tenant = principal.TenantID
+		case principal.HasRole(models.RolePlatformAdmin):
+			// арендатор из заголовка или по умолчанию
+		case h.cfg.DefaultTenant == "":
+			logger.Warn("credentials are not bound to a tenant")
+			newErrorResponse(c, http.StatusForbidden, "credentials are not bound to a tenant")
+			return
+		case header != "" && header != h.cfg.DefaultTenant:
+			logger.Warn("tenant header is not allowed for credentials", "tenant", header)
+			newErrorResponse(c, http.StatusForbidden, "credentials are not valid for the requested tenant")
+			return
+		default:
+			tenant = h.cfg.DefaultTenant
+		}
+	}
+	if tenant == "" {
+		tenant = h.cfg.DefaultTenant
+	}
+
+	switch {
+	case tenant == "":
+		logger.Warn("tenant is not specified")
+		newErrorResponse(c, http.StatusBadRequest, h.cfg.TenantHeader+" header is required")
+		return
+	case !models.ValidTenantID(tenant):
+		logger.Warn("invalid tenant id", "tenant", tenant)
+		newErrorResponse(c, http.StatusBadRequest, "invalid tenant id")
+		return
+	}
+
+	c.Set(tenantKey, tenant)
+	c.Request = c.Request.WithContext(models.ContextWithTenant(c.Request.Context(), tenant))
+	c.Set(loggerKey, logger.With("tenant_id", tenant))
+	c.Next()
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const header = "X-Tenant-ID"
+	user := &models.Principal{Subject: "60601fee-2bf1-4721-ae6f-7636e79a0cba", Method: models.AuthMethodJWT}
+	bound := &models.Principal{Subject: "billing", Method: models.AuthMethodAPIKey, TenantID: "acme"}
+	platformAdmin := &models.Principal{Subject: "ops", Roles: []string{models.RolePlatformAdmin}, Method: models.AuthMethodJWT}
+
+	tests := []struct {
+		name          string
+		principal     *models.Principal
+		defaultTenant string
+		header        string
+		wantStatus    int
+		wantTenant    string
+	}{
+		{name: "bound credentials", principal: bound, defaultTenant: "default", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound credentials with own tenant header", principal: bound, header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound credentials with another tenant header", principal: bound, header: "globex", wantStatus: http.StatusForbidden},
+		{name: "platform admin selects tenant", principal: platformAdmin, defaultTenant: "default", header: "globex", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "platform admin without header", principal: platformAdmin, defaultTenant: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "platform admin without header or default", principal: platformAdmin, wantStatus: http.StatusBadRequest},
+		{name: "unbound user gets default tenant", principal: user, defaultTenant: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "unbound user with default tenant header", principal: user, defaultTenant: "default", header: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "unbound user with another tenant header", principal: user, defaultTenant: "default", header: "globex", wantStatus: http.StatusForbidden},
+		{name: "unbound user without default tenant", principal: user, wantStatus: http.StatusForbidden},
+		{name: "authentication disabled", header: "globex", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "authentication disabled without header or default", wantStatus: http.StatusBadRequest},
+		{name: "invalid tenant id", principal: platformAdmin, header: "acme corp", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+				cfg:    config.HTTP{TenantHeader: header, DefaultTenant: tt.defaultTenant},
+			}
+
+			var tenant string
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				if tt.principal != nil {
+					c.Set(principalKey, *tt.principal)
+				}
+			}, h.resolveTenant, func(c *gin.Context) {
+				tenant, _ = models.TenantFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tenant != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", tenant, tt.wantTenant)
+			}
+		})
+	}
+}
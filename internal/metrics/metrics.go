@@ -0,0 +1,163 @@
+// Package metrics содержит метрики сервиса в формате Prometheus
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace — префикс имён всех метрик сервиса
+const namespace = "subscription_api"
+
+// statsTimeout ограничивает запрос бизнес-метрик к базе при сборе метрик
+const statsTimeout = 5 * time.Second
+
+// statsCacheTTL — сколько переиспользуются бизнес-метрики: запрос к базе
+// выполняется не чаще раза за этот срок, как бы часто ни собирались метрики
+const statsCacheTTL = 30 * time.Second
+
+// Metrics хранит метрики сервиса и собственный реестр, в котором они зарегистрированы
+type Metrics struct {
+	registry *prometheus.Registry
+	logger   *slog.Logger
+
+	HTTPRequests         *prometheus.CounterVec
+	HTTPRequestDuration  *prometheus.HistogramVec
+	SubscriptionsCreated prometheus.Counter
+	SubscriptionsDeleted prometheus.Counter
+}
+
+func New(logger *slog.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		logger:   logger,
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		SubscriptionsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscriptions_created_total",
+			Help:      "Number of subscriptions created.",
+		}),
+		SubscriptionsDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscriptions_deleted_total",
+			Help:      "Number of subscriptions deleted.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.SubscriptionsCreated,
+		m.SubscriptionsDeleted,
+	)
+	return m
+}
+
+// RegisterDB добавляет метрики пула соединений из sql.DBStats
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterSubscriptionStats добавляет бизнес-метрики. Они рассчитываются
+// вызовом stats при сборе метрик и кешируются на statsCacheTTL.
+func (m *Metrics) RegisterSubscriptionStats(stats func(ctx context.Context) (models.SubscriptionStats, error)) {
+	m.registry.MustRegister(&subscriptionStatsCollector{stats: stats})
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus. Ошибка одного коллектора
+// не мешает отдать остальные метрики.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(m.logger.Handler(), slog.LevelError),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+var (
+	activeSubscriptionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "subscriptions_active"),
+		"Number of subscriptions active in the current month across all tenants.",
+		nil, nil,
+	)
+	monthlySpendDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "monthly_recurring_spend"),
+		"Total monthly price of subscriptions active in the current month across all tenants, by service.",
+		[]string{"service_name"}, nil,
+	)
+)
+
+// subscriptionStatsCollector запрашивает статистику подписок при сборе метрик.
+// Метрики не разделяются по арендаторам: /metrics доступен без аутентификации.
+type subscriptionStatsCollector struct {
+	stats func(ctx context.Context) (models.SubscriptionStats, error)
+
+	mu        sync.Mutex
+	cached    models.SubscriptionStats
+	fetchedAt time.Time
+}
+
+func (c *subscriptionStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSubscriptionsDesc
+	ch <- monthlySpendDesc
+}
+
+func (c *subscriptionStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.load()
+	if err != nil {
+		// Ошибку в журнал запишет обработчик /metrics
+		ch <- prometheus.NewInvalidMetric(activeSubscriptionsDesc, err)
+		return
+	}
+
+	// Подписка относится ровно к одному сервису, поэтому число активных
+	// подписок — сумма по сервисам
+	var active int64
+	for _, service := range stats.Services {
+		active += service.Active
+		ch <- prometheus.MustNewConstMetric(monthlySpendDesc, prometheus.GaugeValue,
+			float64(service.MonthlySpend), service.ServiceName)
+	}
+	ch <- prometheus.MustNewConstMetric(activeSubscriptionsDesc, prometheus.GaugeValue, float64(active))
+}
+
+// load возвращает статистику из кеша или запрашивает её, если кеш устарел.
+// Одновременные сборы ждут один запрос к базе; ошибка не кешируется.
+func (c *subscriptionStatsCollector) load() (models.SubscriptionStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < statsCacheTTL {
+		return c.cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	stats, err := c.stats(ctx)
+	if err != nil {
+		return models.SubscriptionStats{}, err
+	}
+	c.cached, c.fetchedAt = stats, time.Now()
+	return stats, nil
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+)
+
+func TestSubscriptionStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		stats      models.SubscriptionStats
+		err        error
+		wantCalls  int
+		wantActive float64
+		wantSpend  map[string]float64
+	}{
+		{
+			name: "aggregated across tenants and cached",
+			stats: models.SubscriptionStats{Services: []models.ServiceStats{
+				{ServiceName: "Netflix", Active: 3, MonthlySpend: 1200},
+				{ServiceName: "Spotify", Active: 2, MonthlySpend: 338},
+			}},
+			wantCalls:  1,
+			wantActive: 5,
+			wantSpend:  map[string]float64{"Netflix": 1200, "Spotify": 338},
+		},
+		{
+			name:      "errors are not cached",
+			err:       errors.New("connection refused"),
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			m := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			m.RegisterSubscriptionStats(func(context.Context) (models.SubscriptionStats, error) {
+				calls++
+				return tt.stats, tt.err
+			})
+
+			var active float64
+			spend := make(map[string]float64)
+			for range 2 {
+				families, _ := m.registry.Gather()
+				for _, family := range families {
+					for _, metric := range family.GetMetric() {
+						switch family.GetName() {
+						case namespace + "_subscriptions_active":
+							if len(metric.GetLabel()) != 0 {
+								t.Errorf("subscriptions_active labels = %v, want none", metric.GetLabel())
+							}
+							active = metric.GetGauge().GetValue()
+						case namespace + "_monthly_recurring_spend":
+							labels := metric.GetLabel()
+							if len(labels) != 1 || labels[0].GetName() != "service_name" {
+								t.Fatalf("monthly_recurring_spend labels = %v, want only service_name", labels)
+							}
+							spend[labels[0].GetValue()] = metric.GetGauge().GetValue()
+						}
+					}
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("stats called %d times, want %d", calls, tt.wantCalls)
+			}
+			if active != tt.wantActive {
+				t.Errorf("subscriptions_active = %v, want %v", active, tt.wantActive)
+			}
+			if len(spend) != len(tt.wantSpend) {
+				t.Errorf("monthly_recurring_spend = %v, want %v", spend, tt.wantSpend)
+			}
+			for service, want := range tt.wantSpend {
+				if spend[service] != want {
+					t.Errorf("monthly_recurring_spend{service_name=%q} = %v, want %v", service, spend[service], want)
+				}
+			}
+		})
+	}
+}
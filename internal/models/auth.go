@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Способы аутентификации принципала
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// RoleAdmin даёт доступ к подпискам всех пользователей
+const RoleAdmin = "admin"
+
+// RolePlatformAdmin разрешает вызывающему без арендатора в учётных данных
+// выбирать арендатора заголовком запроса
+const RolePlatformAdmin = "platform_admin"
+
+// Principal — аутентифицированный вызывающий: пользователь из JWT
+// или сервис, предъявивший API-ключ
+type Principal struct {
+	// Subject — claim sub токена или имя API-ключа
+	Subject string
+	Roles   []string
+	Method  string
+	// TenantID — арендатор из claim tenant_id токена или из настроек API-ключа;
+	// пустой, если учётные данные к арендатору не привязаны
+	TenantID string
+}
+
+// HasRole сообщает, выдана ли принципалу роль role
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal возвращает контекст, содержащий принципала
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext возвращает принципала из контекста
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
+
+type ownerContextKey struct{}
+
+// ContextWithOwner возвращает контекст, в котором подписки по ID читаются и изменяются
+// только у пользователя userID: хранилище добавляет владельца в условие запроса,
+// поэтому проверка доступа и изменение выполняются одной командой
+func ContextWithOwner(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ownerContextKey{}, userID)
+}
+
+// OwnerFromContext возвращает пользователя, которым ограничен доступ к подпискам
+func OwnerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(ownerContextKey{}).(uuid.UUID)
+	return userID, ok
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Доменные ошибки. Слои repository и service оборачивают их через %w,
+// handler сопоставляет их с HTTP-статусами.
+var (
+	// ErrNotFound возвращается, когда запрошенная запись отсутствует
+	ErrNotFound = errors.New("not found")
+	// ErrConflict возвращается, когда операция противоречит текущему состоянию данных
+	ErrConflict = errors.New("conflict")
+	// ErrValidation возвращается, когда входные данные не прошли проверку
+	ErrValidation = errors.New("validation failed")
+	// ErrPreconditionFailed возвращается, когда версия записи не совпала с ожидаемой клиентом
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUnavailable возвращается, когда хранилище временно недоступно
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrForbidden возвращается, когда вызывающему запрещена операция целиком.
+	// Доступ к чужой записи вместо неё отклоняется с ErrNotFound,
+	// чтобы по ответу нельзя было узнать о существовании записи.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// FieldError описывает ошибку проверки одного поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError содержит ошибки проверки входных данных по полям.
+// errors.Is(err, ErrValidation) для неё возвращает true.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError создаёт ошибку проверки одного поля
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// Add добавляет ошибку проверки поля
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// OrNil возвращает e, если ошибки накоплены, и nil в противном случае
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ConflictError — конфликт с состоянием данных, причину которого можно сообщить клиенту.
+// errors.Is(err, ErrConflict) для неё возвращает true.
+type ConflictError struct {
+	Reason string
+}
+
+// NewConflictError создаёт конфликт с причиной reason
+func NewConflictError(reason string) *ConflictError {
+	return &ConflictError{Reason: reason}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Reason
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDomainErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		target    error
+		wantIs    bool
+		wantError string
+	}{
+		{
+			name:      "validation error of several fields",
+			err:       &ValidationError{Fields: []FieldError{{Field: "price", Message: "price must be positive"}, {Field: "user_id", Message: "user_id is required"}}},
+			target:    ErrValidation,
+			wantIs:    true,
+			wantError: "price must be positive; user_id is required",
+		},
+		{
+			name:      "wrapped validation error",
+			err:       fmt.Errorf("SubscriptionService Create() %w", NewValidationError("end_date", "end_date must be after start_date")),
+			target:    ErrValidation,
+			wantIs:    true,
+			wantError: "SubscriptionService Create() end_date must be after start_date",
+		},
+		{
+			name:      "validation error is not a conflict",
+			err:       NewValidationError("price", "price must be positive"),
+			target:    ErrConflict,
+			wantError: "price must be positive",
+		},
+		{
+			name:      "conflict with reason",
+			err:       fmt.Errorf("SubscriptionPostgres Cancel() %w", NewConflictError("subscription is already cancelled")),
+			target:    ErrConflict,
+			wantIs:    true,
+			wantError: "SubscriptionPostgres Cancel() subscription is already cancelled",
+		},
+		{
+			name:      "conflict is not not found",
+			err:       NewConflictError("subscription has ended"),
+			target:    ErrNotFound,
+			wantError: "subscription has ended",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.wantIs {
+				t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.wantIs)
+			}
+			if got := tt.err.Error(); got != tt.wantError {
+				t.Errorf("Error() = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidationErrorOrNil(t *testing.T) {
+	var empty ValidationError
+	if err := empty.OrNil(); err != nil {
+		t.Errorf("OrNil() without fields = %v, want nil", err)
+	}
+
+	var verr ValidationError
+	verr.Add("price", "price must be positive")
+	if err := verr.OrNil(); !errors.Is(err, ErrValidation) {
+		t.Errorf("OrNil() with a field = %v, want ErrValidation", err)
+	}
+}
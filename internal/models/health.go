@@ -0,0 +1,16 @@
+package models
+
+// BuildInfo описывает собранный бинарник
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Readiness — результат проверки готовности сервиса принимать запросы
+type Readiness struct {
+	// SchemaVersion — версия последней применённой миграции (0 — миграции не применялись)
+	SchemaVersion int64     `json:"schema_version"`
+	Build         BuildInfo `json:"build"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const IdempotencyKeyTable = "idempotency_key"
+
+// IdempotencyRecord хранит результат первого запроса с данным ключом идемпотентности.
+// Ключ уникален в пределах Scope (метод и маршрут). StatusCode == 0 означает,
+// что первый запрос ещё выполняется; до LockedUntil ключ за ним закреплён,
+// после — его может занять повтор.
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+	LockedUntil time.Time
+}
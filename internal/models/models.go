@@ -1,6 +1,8 @@
 package models
 
 import (
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,8 +19,180 @@ type Subscription struct {
 	UserID      uuid.UUID  `json:"user_id"`
 	StartDate   time.Time  `json:"start_date"`
 	EndDate     *time.Time `json:"end_date,omitempty"`
+	// Цена списывается каждые billing_interval_count единиц billing_interval;
+	// первое списание — в день billing_anchor_day месяца начала подписки.
+	BillingInterval      string `json:"billing_interval" enums:"day,week,month,year"`
+	BillingIntervalCount int    `json:"billing_interval_count"`
+	BillingAnchorDay     int    `json:"billing_anchor_day"`
+	// MonthlyPrice — цена в пересчёте на месяц, см. MonthlyEquivalent
+	MonthlyPrice int64 `json:"monthly_price"`
+	// PriceHistory — цены подписки по месяцам, с которых они действуют; Price — цена,
+	// действующая сейчас. История возвращается только при чтении одной подписки.
+	PriceHistory []PriceChange `json:"price_history,omitempty"`
+	// TrialEnd — последний месяц бесплатного пробного периода; списания начинаются
+	// со следующего месяца. InTrial — подписка началась и пробный период идёт сейчас.
+	TrialEnd *time.Time `json:"trial_end,omitempty"`
+	InTrial  bool       `json:"in_trial"`
+	// Promotions — промо-периоды подписки; возвращаются только при чтении одной подписки
+	Promotions []Promotion `json:"promotions,omitempty"`
+	// State — состояние подписки в текущем месяце, см. StateActive и др.
+	State string `json:"state" enums:"scheduled,active,paused,ended"`
+	// Pauses — приостановки подписки; возвращаются только при чтении одной подписки
+	Pauses []Pause `json:"pauses,omitempty"`
+	// Cancellation — действующая отмена подписки; возвращается только при чтении одной подписки
+	Cancellation *Cancellation `json:"cancellation,omitempty"`
+	// Version увеличивается при каждом изменении записи и отдаётся клиентам как ETag
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// PriceChange model
+// Цена подписки, действующая с месяца effective_from до следующего изменения
+// @name PriceChange
+type PriceChange struct {
+	EffectiveFrom time.Time `json:"effective_from"`
+	Price         int64     `json:"price"`
+}
+
+// Promotion model
+// Промо-период: в месяцах start_month..end_month (включительно) списывается цена price
+// вместо обычной; price = 0 — бесплатные месяцы
+// @name Promotion
+type Promotion struct {
+	StartMonth time.Time `json:"start_month"`
+	EndMonth   time.Time `json:"end_month"`
+	Price      int64     `json:"price"`
+}
+
+// Pause model
+// Приостановка: месяцы start_month..end_month (включительно) не оплачиваются;
+// без end_month подписка приостановлена до возобновления
+// @name Pause
+type Pause struct {
+	StartMonth time.Time  `json:"start_month"`
+	EndMonth   *time.Time `json:"end_month,omitempty"`
+}
+
+// Состояния подписки в текущем месяце
+const (
+	StateScheduled = "scheduled" // подписка ещё не началась
+	StateActive    = "active"
+	StatePaused    = "paused" // текущий месяц входит в приостановку
+	StateEnded     = "ended"  // месяц окончания подписки прошёл
+)
+
+// Причины отмены подписки
+const (
+	CancelReasonTooExpensive    = "too_expensive"
+	CancelReasonNotUsing        = "not_using"
+	CancelReasonSwitched        = "switched_service"
+	CancelReasonMissingFeatures = "missing_features"
+	CancelReasonTechnical       = "technical_issues"
+	CancelReasonOther           = "other"
+)
+
+// CancelReasons — допустимые причины отмены подписки
+var CancelReasons = []string{
+	CancelReasonTooExpensive,
+	CancelReasonNotUsing,
+	CancelReasonSwitched,
+	CancelReasonMissingFeatures,
+	CancelReasonTechnical,
+	CancelReasonOther,
+}
+
+// Момент, с которого действует отмена подписки
+const (
+	CancelImmediately = "immediately"   // последний оплачиваемый месяц — текущий
+	CancelEndOfPeriod = "end_of_period" // подписка действует до конца оплаченного периода
+	CancelAtMonth     = "month"         // последний оплачиваемый месяц задан явно
+)
+
+// CancelRequest описывает отмену подписки
+type CancelRequest struct {
+	Reason string
+	Note   string
+	When   string    // CancelImmediately, CancelEndOfPeriod или CancelAtMonth
+	Month  time.Time // последний оплачиваемый месяц для CancelAtMonth
+}
+
+// Cancellation model
+// Отмена подписки: effective_month — последний оплачиваемый месяц, он же end_date подписки
+// @name Cancellation
+type Cancellation struct {
+	Reason         string    `json:"reason" enums:"too_expensive,not_using,switched_service,missing_features,technical_issues,other"`
+	Note           string    `json:"note,omitempty"`
+	EffectiveMonth time.Time `json:"effective_month"`
+	CancelledAt    time.Time `json:"cancelled_at"`
+}
+
+// CancellationReasonStats model
+// Число отмен с одной причиной и цена отменённых подписок в пересчёте на месяц
+// @name CancellationReasonStats
+type CancellationReasonStats struct {
+	Reason           string `json:"reason"`
+	Cancellations    int64  `json:"cancellations"`
+	LostMonthlySpend int64  `json:"lost_monthly_spend"`
+}
+
+// Единицы интервала оплаты подписки
+const (
+	BillingDay   = "day"
+	BillingWeek  = "week"
+	BillingMonth = "month"
+	BillingYear  = "year"
+)
+
+// BillingUnitsPerMonth — среднее число единиц интервала оплаты в месяце (в году 365,25 дня)
+var BillingUnitsPerMonth = map[string]float64{
+	BillingDay:   365.25 / 12,
+	BillingWeek:  365.25 / 12 / 7,
+	BillingMonth: 1,
+	BillingYear:  1.0 / 12,
+}
+
+// MonthlyEquivalent возвращает цену подписки в пересчёте на месяц, округлённую до рубля
+func (s Subscription) MonthlyEquivalent() int64 {
+	if s.BillingIntervalCount <= 0 {
+		return s.Price
+	}
+	return int64(math.Round(float64(s.Price) * BillingUnitsPerMonth[s.BillingInterval] / float64(s.BillingIntervalCount)))
+}
+
+// SubscriptionPatch описывает частичное обновление подписки: изменяются
+// только поля, отличные от nil. ClearEndDate сбрасывает дату окончания в NULL.
+type SubscriptionPatch struct {
+	ServiceName  *string
+	Price        *int64
+	UserID       *uuid.UUID
+	StartDate    *time.Time
+	EndDate      *time.Time
+	ClearEndDate bool
+
+	BillingInterval      *string
+	BillingIntervalCount *int
+	BillingAnchorDay     *int
+
+	TrialEnd      *time.Time
+	ClearTrialEnd bool
+	// Promotions, отличный от nil, заменяет все промо-периоды подписки
+	Promotions *[]Promotion
+}
+
+// IsEmpty сообщает, что патч не изменяет ни одного поля
+func (p SubscriptionPatch) IsEmpty() bool {
+	return p.ServiceName == nil && p.Price == nil && p.UserID == nil &&
+		p.StartDate == nil && p.EndDate == nil && !p.ClearEndDate &&
+		p.BillingInterval == nil && p.BillingIntervalCount == nil && p.BillingAnchorDay == nil &&
+		p.TrialEnd == nil && !p.ClearTrialEnd && p.Promotions == nil
+}
+
+// SubscriptionParams содержит параметры выборки и расчёта стоимости подписок.
+//
+// StartDate и EndDate задают период расчёта стоимости с точностью до месяца:
+// оба значения — первое число месяца, оба месяца входят в период.
+// Стоимость — сумма списаний подписки, даты которых попадают в период, по её
+// интервалу оплаты; подписка без даты окончания считается активной до конца периода.
 type SubscriptionParams struct {
 	Page        int
 	Limit       int
@@ -26,4 +200,120 @@ type SubscriptionParams struct {
 	ServiceName string
 	StartDate   time.Time
 	EndDate     time.Time
+
+	// Фильтры списка подписок. Даты — первое число месяца, границы включительно.
+	ServiceNamePrefix string
+	PriceMin          *int64
+	PriceMax          *int64
+	ActiveOn          *time.Time // подписка активна в этом месяце
+	StartDateFrom     *time.Time
+	StartDateTo       *time.Time
+	EndDateFrom       *time.Time
+	EndDateTo         *time.Time
+	HasEndDate        *bool
+	InTrial           *bool
+	// TrialEndingWithinDays отбирает подписки, пробный период которых
+	// закончится не позже чем через указанное число дней
+	TrialEndingWithinDays *int
+	State                 string // состояние подписки в текущем месяце
+	// Sort задаёт порядок списка; при равенстве ключей записи упорядочиваются по id.
+	Sort []SortField
+	// CursorPaging включает постраничное чтение по ключу вместо LIMIT/OFFSET:
+	// Page игнорируется, выборка начинается после позиции After (nil — с начала).
+	CursorPaging bool
+	After        *Cursor
+
+	// GroupBy задаёт дополнительные измерения помесячной разбивки стоимости:
+	// CostGroupServiceName и/или CostGroupUserID.
+	GroupBy []string
+}
+
+// SortField описывает один ключ сортировки списка подписок
+type SortField struct {
+	Field string `json:"f"`
+	Desc  bool   `json:"d,omitempty"`
+}
+
+// Допустимые ключи сортировки списка подписок
+const (
+	SortServiceName = "service_name"
+	SortPrice       = "price"
+	SortUserID      = "user_id"
+	SortStartDate   = "start_date"
+	SortEndDate     = "end_date"
+)
+
+// Cursor указывает позицию в списке подписок для постраничного чтения по ключу.
+// Values содержит значения ключей сортировки последней прочитанной записи
+// в порядке Sort, ID — её идентификатор.
+type Cursor struct {
+	Sort   []SortField `json:"s,omitempty"`
+	Values []string    `json:"v,omitempty"`
+	ID     uuid.UUID   `json:"id"`
+}
+
+// NewCursor строит курсор, указывающий на подписку sub при сортировке sort.
+// Отсутствующая дата окончания кодируется как "infinity": в сортировке
+// бессрочные подписки идут после всех остальных.
+func NewCursor(sub Subscription, sort []SortField) Cursor {
+	cursor := Cursor{Sort: sort, ID: sub.ID}
+	for _, field := range sort {
+		var value string
+		switch field.Field {
+		case SortServiceName:
+			value = sub.ServiceName
+		case SortPrice:
+			value = strconv.FormatInt(sub.Price, 10)
+		case SortUserID:
+			value = sub.UserID.String()
+		case SortStartDate:
+			value = sub.StartDate.Format(time.DateOnly)
+		case SortEndDate:
+			value = "infinity"
+			if sub.EndDate != nil {
+				value = sub.EndDate.Format(time.DateOnly)
+			}
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	return cursor
+}
+
+// SubscriptionList содержит страницу списка подписок. При постраничном чтении
+// по смещению Total — общее число записей, удовлетворяющих фильтрам;
+// при чтении по курсору NextCursor указывает на следующую страницу (nil — страниц больше нет).
+type SubscriptionList struct {
+	Subscriptions []Subscription
+	Total         int64
+	NextCursor    *Cursor
+}
+
+// Допустимые измерения группировки разбивки стоимости
+const (
+	CostGroupServiceName = "service_name"
+	CostGroupUserID      = "user_id"
+)
+
+// CostBucket model
+// Сумма списаний по подпискам за один календарный месяц периода,
+// при группировке — в разрезе сервиса и/или пользователя.
+// @name CostBucket
+type CostBucket struct {
+	Month         time.Time  `json:"month"`
+	ServiceName   string     `json:"service_name,omitempty"`
+	UserID        *uuid.UUID `json:"user_id,omitempty"`
+	Total         int64      `json:"total"`
+	Subscriptions int64      `json:"subscriptions"`
+}
+
+// SubscriptionStats — сводка по подпискам, активным в заданном месяце
+type SubscriptionStats struct {
+	Services []ServiceStats
+}
+
+// ServiceStats — активные подписки одного сервиса у всех арендаторов и их суммарная цена за месяц
+type ServiceStats struct {
+	ServiceName  string
+	Active       int64
+	MonthlySpend int64
 }
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestMonthlyEquivalent(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    int64
+		interval string
+		count    int
+		want     int64
+	}{
+		{name: "monthly", price: 400, interval: BillingMonth, count: 1, want: 400},
+		{name: "every three months", price: 400, interval: BillingMonth, count: 3, want: 133},
+		{name: "yearly", price: 1200, interval: BillingYear, count: 1, want: 100},
+		{name: "every two years", price: 2400, interval: BillingYear, count: 2, want: 100},
+		{name: "weekly", price: 100, interval: BillingWeek, count: 1, want: 435},
+		{name: "every two weeks", price: 100, interval: BillingWeek, count: 2, want: 217},
+		{name: "daily", price: 10, interval: BillingDay, count: 1, want: 304},
+		{name: "rounds half up", price: 6, interval: BillingMonth, count: 4, want: 2},
+		{name: "no interval count", price: 400, interval: BillingMonth, count: 0, want: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Subscription{Price: tt.price, BillingInterval: tt.interval, BillingIntervalCount: tt.count}
+			if got := s.MonthlyEquivalent(); got != tt.want {
+				t.Errorf("MonthlyEquivalent(%d per %d %s) = %d, want %d", tt.price, tt.count, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
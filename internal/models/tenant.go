@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+	"regexp"
+)
+
+// tenantIDRe — допустимый формат идентификатора арендатора
+var tenantIDRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidTenantID сообщает, может ли id быть идентификатором арендатора
+func ValidTenantID(id string) bool {
+	return tenantIDRe.MatchString(id)
+}
+
+type tenantContextKey struct{}
+
+// ContextWithTenant возвращает контекст, в котором запросы выполняются от имени арендатора tenantID
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext возвращает арендатора из контекста
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+)
+
+// Limiter применяет лимиты маршрутов к вызывающим
+type Limiter struct {
+	store Store
+	// fallback — лимит маршрутов, не перечисленных в routes; nil — без ограничения
+	fallback *Limit
+	routes   map[string]Limit
+	// address — лимит запросов с одного IP-адреса; nil — без ограничения
+	address *Limit
+	now     func() time.Time
+}
+
+// New создаёт Limiter по конфигурации. Маршрут задаётся методом и шаблоном пути,
+// например "POST /subscription/cost".
+func New(cfg config.RateLimit, store Store) (*Limiter, error) {
+	l := &Limiter{
+		store:  store,
+		routes: make(map[string]Limit),
+		now:    time.Now,
+	}
+
+	if cfg.Default != "" {
+		limit, err := ParseLimit(cfg.Default)
+		if err != nil {
+			return nil, err
+		}
+		l.fallback = &limit
+	}
+
+	if cfg.Address != "" {
+		limit, err := ParseLimit(cfg.Address)
+		if err != nil {
+			return nil, err
+		}
+		l.address = &limit
+	}
+
+	for _, entry := range cfg.Routes {
+		route, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || route == "" {
+			return nil, fmt.Errorf("ratelimit: invalid route limit %q, expected \"METHOD /path=limit\"", entry)
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		l.routes[strings.TrimSpace(route)] = limit
+	}
+
+	return l, nil
+}
+
+// Take расходует один запрос вызывающего identity на маршруте route.
+// ok = false, если для маршрута лимит не задан.
+func (l *Limiter) Take(ctx context.Context, route, identity string) (_ Result, ok bool, _ error) {
+	limit, ok := l.routes[route]
+	if !ok {
+		if l.fallback == nil {
+			return Result{}, false, nil
+		}
+		limit = *l.fallback
+	}
+
+	res, err := l.store.Take(ctx, route+" "+identity, limit, l.now())
+	if err != nil {
+		return Result{}, true, fmt.Errorf("ratelimit: %w", err)
+	}
+	return res, true, nil
+}
+
+// TakeAddress расходует один запрос с IP-адреса ip, общий для всех маршрутов.
+// ok = false, если лимит адреса не задан.
+func (l *Limiter) TakeAddress(ctx context.Context, ip string) (_ Result, ok bool, _ error) {
+	if l.address == nil {
+		return Result{}, false, nil
+	}
+
+	res, err := l.store.Take(ctx, "* ip:"+ip, *l.address, l.now())
+	if err != nil {
+		return Result{}, true, fmt.Errorf("ratelimit: %w", err)
+	}
+	return res, true, nil
+}
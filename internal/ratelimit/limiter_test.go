@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RateLimit
+		wantErr bool
+	}{
+		{name: "empty", cfg: config.RateLimit{}},
+		{name: "valid", cfg: config.RateLimit{Default: "600/1m", Address: "1200/1m", Routes: []string{"POST /subscription/cost=60/1m"}}},
+		{name: "invalid default", cfg: config.RateLimit{Default: "600"}, wantErr: true},
+		{name: "invalid address", cfg: config.RateLimit{Address: "fast"}, wantErr: true},
+		{name: "route without limit", cfg: config.RateLimit{Routes: []string{"POST /subscription/cost"}}, wantErr: true},
+		{name: "limit without route", cfg: config.RateLimit{Routes: []string{"=60/1m"}}, wantErr: true},
+		{name: "invalid route limit", cfg: config.RateLimit{Routes: []string{"POST /subscription/cost=60"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, NewMemoryStore())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimiterTake(t *testing.T) {
+	cfg := config.RateLimit{
+		Default: "100/1m",
+		Routes:  []string{" POST /subscription/cost = 1/1m "},
+	}
+	tests := []struct {
+		name      string
+		cfg       config.RateLimit
+		route     string
+		wantOK    bool
+		wantLimit Limit
+	}{
+		{name: "route limit", cfg: cfg, route: "POST /subscription/cost", wantOK: true, wantLimit: Limit{Requests: 1, Period: time.Minute, Burst: 1}},
+		{name: "default limit", cfg: cfg, route: "GET /subscription/:id", wantOK: true, wantLimit: Limit{Requests: 100, Period: time.Minute, Burst: 100}},
+		{name: "no default", cfg: config.RateLimit{Routes: cfg.Routes}, route: "GET /subscription/:id", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := New(tt.cfg, NewMemoryStore())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			res, ok, err := limiter.Take(context.Background(), tt.route, "api_key:billing")
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("Take() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && res.Limit != tt.wantLimit {
+				t.Errorf("Take() limit = %+v, want %+v", res.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestLimiterTakeAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		ips     []string
+		want    []bool
+	}{
+		{name: "disabled", address: "", ips: []string{"10.0.0.1", "10.0.0.1"}, want: []bool{true, true}},
+		{name: "same address", address: "1/1m", ips: []string{"10.0.0.1", "10.0.0.1"}, want: []bool{true, false}},
+		{name: "different addresses", address: "1/1m", ips: []string{"10.0.0.1", "10.0.0.2"}, want: []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := New(config.RateLimit{Address: tt.address}, NewMemoryStore())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for i, ip := range tt.ips {
+				res, ok, err := limiter.TakeAddress(context.Background(), ip)
+				if err != nil {
+					t.Fatalf("TakeAddress(%s) error = %v", ip, err)
+				}
+				allowed := !ok || res.Allowed
+				if allowed != tt.want[i] {
+					t.Errorf("request %d from %s: allowed = %v, want %v", i, ip, allowed, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterAddressIsSeparateFromRoutes(t *testing.T) {
+	limiter, err := New(config.RateLimit{Default: "1/1m", Address: "1/1m"}, NewMemoryStore())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if res, _, _ := limiter.TakeAddress(context.Background(), "10.0.0.1"); !res.Allowed {
+		t.Fatal("first address request is rejected")
+	}
+	if res, _, _ := limiter.Take(context.Background(), "GET /subscription/:id", "ip:10.0.0.1"); !res.Allowed {
+		t.Error("route limit is spent by the address limit")
+	}
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval — как часто MemoryStore удаляет наполнившиеся корзины
+const memorySweepInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса. Подходит для одного экземпляра сервиса.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	// full — момент, когда корзина наполнится и её можно будет забыть
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryBucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	bucket, res := s.buckets[key].Take(limit, now)
+	s.buckets[key] = memoryBucket{Bucket: bucket, full: now.Add(res.Reset)}
+	return res, nil
+}
+
+// sweep удаляет полные корзины: новая корзина для того же ключа будет такой же
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if !bucket.full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
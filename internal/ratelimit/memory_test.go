@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Minute, Burst: 2}
+	start := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	type take struct {
+		key  string
+		at   time.Duration
+		want bool
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst is spent and then rejected",
+			takes: []take{
+				{key: "a", want: true},
+				{key: "a", want: true},
+				{key: "a", want: false},
+			},
+		},
+		{
+			name: "keys are limited separately",
+			takes: []take{
+				{key: "a", want: true},
+				{key: "a", want: true},
+				{key: "b", want: true},
+				{key: "a", want: false},
+			},
+		},
+		{
+			name: "tokens are refilled over time",
+			takes: []take{
+				{key: "a", want: true},
+				{key: "a", want: true},
+				{key: "a", at: 10 * time.Second, want: false},
+				{key: "a", at: 30 * time.Second, want: true},
+			},
+		},
+		{
+			name: "swept bucket starts full",
+			takes: []take{
+				{key: "a", want: true},
+				{key: "a", want: true},
+				{key: "a", at: 2 * memorySweepInterval, want: true},
+				{key: "a", at: 2 * memorySweepInterval, want: true},
+				{key: "a", at: 2 * memorySweepInterval, want: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, tk := range tt.takes {
+				res, err := store.Take(context.Background(), tk.key, limit, start.Add(tk.at))
+				if err != nil {
+					t.Fatalf("take %d: error = %v", i, err)
+				}
+				if res.Allowed != tk.want {
+					t.Errorf("take %d (%s at %v): allowed = %v, want %v", i, tk.key, tk.at, res.Allowed, tk.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 60}
+	start := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := store.Take(context.Background(), key, limit, start); err != nil {
+			t.Fatalf("Take(%s) error = %v", key, err)
+		}
+	}
+	// Через интервал очистки корзины a и b наполнились и удаляются, c только что использована
+	if _, err := store.Take(context.Background(), "c", limit, start.Add(memorySweepInterval)); err != nil {
+		t.Fatalf("Take(c) error = %v", err)
+	}
+
+	if got := len(store.buckets); got != 1 {
+		t.Errorf("buckets after sweep = %d, want 1", got)
+	}
+	if _, ok := store.buckets["c"]; !ok {
+		t.Error("bucket c was swept")
+	}
+}
@@ -0,0 +1,104 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit — Requests запросов за Period в среднем и не более Burst подряд
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParseLimit разбирает лимит вида запросов/период[:burst], например 60/1m или 10/1s:20.
+// Без burst ёмкость корзины равна числу запросов за период.
+func ParseLimit(s string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	requests, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q, expected requests/period[:burst]", s)
+	}
+
+	var (
+		limit Limit
+		err   error
+	)
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid number of requests in limit %q", s)
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in limit %q", s)
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("ratelimit: invalid burst in limit %q", s)
+		}
+	}
+	return limit, nil
+}
+
+// perSecond — скорость пополнения корзины, токенов в секунду
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result — решение по одному запросу
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining — целое число токенов, оставшихся в корзине
+	Remaining int
+	// RetryAfter — через сколько появится токен; 0, если запрос разрешён
+	RetryAfter time.Duration
+	// Reset — через сколько корзина наполнится полностью
+	Reset time.Duration
+}
+
+// Bucket — состояние корзины: Tokens токенов на момент Updated.
+// Нулевое значение — новая, полная корзина.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take пополняет корзину за время, прошедшее с Updated, и пытается взять из неё
+// один токен. Возвращает новое состояние корзины, которое нужно сохранить.
+func (b Bucket) Take(limit Limit, now time.Time) (Bucket, Result) {
+	burst := float64(limit.Burst)
+	tokens := burst
+	if !b.Updated.IsZero() {
+		// Часы экземпляров могут расходиться: время назад не уменьшает число токенов
+		elapsed := max(now.Sub(b.Updated).Seconds(), 0)
+		tokens = min(b.Tokens+elapsed*limit.perSecond(), burst)
+	}
+
+	res := Result{Limit: limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - tokens) / limit.perSecond())
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = secondsToDuration((burst - tokens) / limit.perSecond())
+
+	return Bucket{Tokens: tokens, Updated: now}, res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Store хранит корзины. Take должен изменять корзину ключа атомарно; чтобы лимит
+// действовал на все экземпляры сервиса, хранилище должно быть общим для них.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{name: "without burst", in: "60/1m", want: Limit{Requests: 60, Period: time.Minute, Burst: 60}},
+		{name: "with burst", in: "10/1s:20", want: Limit{Requests: 10, Period: time.Second, Burst: 20}},
+		{name: "surrounding spaces", in: " 5/10s ", want: Limit{Requests: 5, Period: 10 * time.Second, Burst: 5}},
+		{name: "empty", in: "", wantErr: true},
+		{name: "no period", in: "60", wantErr: true},
+		{name: "zero requests", in: "0/1m", wantErr: true},
+		{name: "negative requests", in: "-1/1m", wantErr: true},
+		{name: "invalid period", in: "60/minute", wantErr: true},
+		{name: "zero period", in: "60/0s", wantErr: true},
+		{name: "invalid burst", in: "60/1m:x", wantErr: true},
+		{name: "zero burst", in: "60/1m:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketTake(t *testing.T) {
+	// 1 токен в секунду, не больше 2 подряд
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 2}
+	start := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		bucket Bucket
+		now    time.Time
+		want   Result
+		tokens float64
+	}{
+		{
+			name:   "new bucket is full",
+			bucket: Bucket{},
+			now:    start,
+			want:   Result{Allowed: true, Limit: limit, Remaining: 1, Reset: time.Second},
+			tokens: 1,
+		},
+		{
+			name:   "last token",
+			bucket: Bucket{Tokens: 1, Updated: start},
+			now:    start,
+			want:   Result{Allowed: true, Limit: limit, Remaining: 0, Reset: 2 * time.Second},
+			tokens: 0,
+		},
+		{
+			name:   "empty bucket is rejected",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start,
+			want:   Result{Allowed: false, Limit: limit, Remaining: 0, RetryAfter: time.Second, Reset: 2 * time.Second},
+			tokens: 0,
+		},
+		{
+			name:   "partial refill is not enough",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start.Add(500 * time.Millisecond),
+			want:   Result{Allowed: false, Limit: limit, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 1500 * time.Millisecond},
+			tokens: 0.5,
+		},
+		{
+			name:   "refill after a second",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start.Add(time.Second),
+			want:   Result{Allowed: true, Limit: limit, Remaining: 0, Reset: 2 * time.Second},
+			tokens: 0,
+		},
+		{
+			name:   "refill is capped by burst",
+			bucket: Bucket{Tokens: 0, Updated: start},
+			now:    start.Add(time.Hour),
+			want:   Result{Allowed: true, Limit: limit, Remaining: 1, Reset: time.Second},
+			tokens: 1,
+		},
+		{
+			name:   "clock going back does not drain the bucket",
+			bucket: Bucket{Tokens: 1, Updated: start},
+			now:    start.Add(-time.Minute),
+			want:   Result{Allowed: true, Limit: limit, Remaining: 0, Reset: 2 * time.Second},
+			tokens: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, got := tt.bucket.Take(limit, tt.now)
+			if got != tt.want {
+				t.Errorf("Take() result = %+v, want %+v", got, tt.want)
+			}
+			if bucket.Tokens != tt.tokens {
+				t.Errorf("Take() tokens = %v, want %v", bucket.Tokens, tt.tokens)
+			}
+			if !bucket.Updated.Equal(tt.now) {
+				t.Errorf("Take() updated = %v, want %v", bucket.Updated, tt.now)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/lib/pq"
+)
+
+// mapDBError дополняет ошибку драйвера доменной ошибкой из models,
+// сохраняя исходную ошибку в цепочке. Нераспознанные ошибки возвращаются как есть.
+func mapDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "23": // нарушение ограничений целостности
+			if pqErr.Code.Name() == "unique_violation" || pqErr.Code.Name() == "exclusion_violation" {
+				return fmt.Errorf("%w: %w", models.ErrConflict, err)
+			}
+			return fmt.Errorf("%w: %w", models.ErrValidation, err)
+		case "22": // некорректные данные
+			return fmt.Errorf("%w: %w", models.ErrValidation, err)
+		case "08", "53", "57": // соединение, нехватка ресурсов, остановка сервера
+			return fmt.Errorf("%w: %w", models.ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", models.ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestMapDBError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, want: models.ErrConflict},
+		{name: "exclusion violation", err: &pq.Error{Code: "23P01"}, want: models.ErrConflict},
+		{name: "check violation", err: &pq.Error{Code: "23514"}, want: models.ErrValidation},
+		{name: "invalid date", err: &pq.Error{Code: "22008"}, want: models.ErrValidation},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, want: models.ErrUnavailable},
+		{name: "too many connections", err: &pq.Error{Code: "53300"}, want: models.ErrUnavailable},
+		{name: "admin shutdown", err: &pq.Error{Code: "57P01"}, want: models.ErrUnavailable},
+		{name: "bad connection", err: driver.ErrBadConn, want: models.ErrUnavailable},
+		{name: "connection done", err: sql.ErrConnDone, want: models.ErrUnavailable},
+		{name: "deadline", err: context.DeadlineExceeded, want: models.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapDBError(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("mapDBError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			// Исходная ошибка остаётся в цепочке
+			if tt.err != nil && !errors.Is(got, tt.err) {
+				t.Errorf("mapDBError(%v) = %v, want the driver error kept in the chain", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestMapDBErrorUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "syntax error", err: &pq.Error{Code: "42601"}},
+		{name: "no rows", err: sql.ErrNoRows},
+		{name: "plain error", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Нераспознанные ошибки не получают доменную ошибку и становятся 500
+			if got := mapDBError(tt.err); got != tt.err {
+				t.Errorf("mapDBError(%v) = %v, want it unchanged", tt.err, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Health interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
+}
+
+type HealthPostgres struct {
+	db *sqlx.DB
+}
+
+func NewHealthPostgres(db *sqlx.DB) *HealthPostgres {
+	return &HealthPostgres{
+		db: db,
+	}
+}
+
+// Ping проверяет, что пул может выдать соединение и база отвечает.
+// Время проверки ограничивает вызывающий через ctx.
+func (r *HealthPostgres) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("HealthPostgres Ping() %w", mapDBError(err))
+	}
+	return nil
+}
+
+func (r *HealthPostgres) SchemaVersion(ctx context.Context) (int64, error) {
+	return CurrentVersion(ctx, r.db)
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type Idempotency interface {
+	Reserve(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+	Release(ctx context.Context, record models.IdempotencyRecord) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type IdempotencyPostgres struct {
+	db      tracedDB
+	timeout time.Duration
+}
+
+func NewIdempotencyPostgres(db *sqlx.DB, timeout time.Duration) *IdempotencyPostgres {
+	return &IdempotencyPostgres{
+		db:      tracedDB{db},
+		timeout: timeout,
+	}
+}
+
+// Reserve занимает ключ идемпотентности для нового запроса. Если ключ свободен,
+// срок его хранения истёк или истекла аренда ключа незавершённым запросом,
+// возвращается nil. Если ключ уже занят, возвращается сохранённая запись —
+// её ответ нужно повторить клиенту.
+func (r *IdempotencyPostgres) Reserve(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := squirrel.Insert(models.IdempotencyKeyTable).
+		Columns("scope", "key", "fingerprint", "expires_at", "locked_until").
+		Values(record.Scope, record.Key, record.Fingerprint, record.ExpiresAt, record.LockedUntil).
+		// Просроченный ключ и ключ, брошенный незавершённым запросом, занимаются
+		// заново, как если бы их не было
+		Suffix("ON CONFLICT (scope, key) DO UPDATE SET " +
+			"fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, " +
+			"response_body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at, " +
+			"locked_until = EXCLUDED.locked_until " +
+			"WHERE " + models.IdempotencyKeyTable + ".expires_at <= now() " +
+			"OR (" + models.IdempotencyKeyTable + ".status_code IS NULL AND " + models.IdempotencyKeyTable + ".locked_until <= now()) " +
+			"RETURNING key").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyPostgres Reserve() ошибка построения SQL-запроса: %w", err)
+	}
+
+	var key string
+	err = r.db.QueryRowContext(ctx, sqlQuery, args...).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("IdempotencyPostgres Reserve() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+
+	// Ключ занят действующей записью — читаем её
+	existing := models.IdempotencyRecord{Scope: record.Scope, Key: record.Key}
+	var (
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		lockedUntil sql.NullTime
+	)
+	err = r.db.QueryRowContext(ctx,
+		"SELECT fingerprint, status_code, content_type, response_body, expires_at, locked_until FROM "+
+			models.IdempotencyKeyTable+" WHERE scope = $1 AND key = $2",
+		record.Scope, record.Key,
+	).Scan(&existing.Fingerprint, &statusCode, &contentType, &existing.Body, &existing.ExpiresAt, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Запись удалили между вставкой и чтением — клиент может повторить запрос
+		return nil, fmt.Errorf("IdempotencyPostgres Reserve() ключ %q освобождён параллельно: %w", record.Key, models.ErrConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyPostgres Reserve() ошибка чтения записи: %w", mapDBError(err))
+	}
+	existing.StatusCode = int(statusCode.Int64)
+	existing.ContentType = contentType.String
+	existing.LockedUntil = lockedUntil.Time
+
+	return &existing, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ. Если аренда истекла и ключ
+// занял повтор, ответ не сохраняется: ключом владеет повтор.
+func (r *IdempotencyPostgres) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := squirrel.Update(models.IdempotencyKeyTable).
+		Set("status_code", record.StatusCode).
+		Set("content_type", record.ContentType).
+		Set("response_body", record.Body).
+		Set("locked_until", nil).
+		Where(squirrel.Eq{"scope": record.Scope, "key": record.Key, "locked_until": record.LockedUntil}).
+		Where("status_code IS NULL").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("IdempotencyPostgres Complete() ошибка построения SQL-запроса: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return fmt.Errorf("IdempotencyPostgres Complete() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	return nil
+}
+
+// Release освобождает ключ, чтобы запрос можно было повторить. Ключ, который
+// после истечения аренды занял повтор, не освобождается.
+func (r *IdempotencyPostgres) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	query := squirrel.Delete(models.IdempotencyKeyTable).
+		Where(squirrel.Eq{"scope": record.Scope, "key": record.Key, "locked_until": record.LockedUntil}).
+		Where("status_code IS NULL").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("IdempotencyPostgres Release() ошибка построения SQL-запроса: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return fmt.Errorf("IdempotencyPostgres Release() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	return nil
+}
+
+// DeleteExpired удаляет записи с истёкшим сроком хранения и возвращает их число
+func (r *IdempotencyPostgres) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM "+models.IdempotencyKeyTable+" WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("IdempotencyPostgres DeleteExpired() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("IdempotencyPostgres DeleteExpired() ошибка получения количества изменённых строк: %w", err)
+	}
+	return deleted, nil
+}
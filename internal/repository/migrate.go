@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// schemaMigrationsTable хранит версии применённых миграций
+const schemaMigrationsTable = "schema_migrations"
+
+// migrationLockID — ключ advisory-блокировки Postgres, под которой выполняются миграции.
+// Блокировка не даёт нескольким репликам сервиса применять миграции одновременно.
+const migrationLockID int64 = 7_318_402_215_560_144_001
+
+// migrationFileRe разбирает имя файла миграции: <версия>_<название>.(up|down).sql
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrNoMigrations возвращается, если в файловой системе нет ни одной миграции
+var ErrNoMigrations = errors.New("no migrations found")
+
+// Migration описывает одну версию схемы
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus описывает состояние миграции в базе
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator применяет и откатывает миграции из файловой системы fsys
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator загружает миграции из fsys. Для каждой версии должны быть и up-, и down-файл.
+func NewMigrator(db *sqlx.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("Migrator ошибка чтения каталога миграций: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Migrator некорректная версия миграции %s: %w", entry.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("Migrator ошибка чтения миграции %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("Migrator у версии %d разные названия: %s и %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("Migrator у миграции %d_%s нет up- или down-файла", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	if len(migrations) == 0 {
+		return nil, ErrNoMigrations
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает применённые
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает последнюю применённую миграцию. Если применённых миграций нет,
+// возвращается nil.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var done *Migration
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				done = &m.migrations[i]
+				return m.apply(ctx, conn, *done, false)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Goto приводит схему к версии version: применяет миграции до неё включительно
+// и откатывает более поздние. Версия 0 откатывает все миграции.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mg Migration) bool { return mg.Version == version }) {
+		return fmt.Errorf("Migrator миграция с версией %d не найдена", version)
+	}
+
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		// Сначала откатываем лишнее, от новых к старым
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.apply(ctx, conn, migration, false); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, conn, migration, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// CurrentVersion возвращает наибольшую применённую версию схемы (0 — миграции не применялись).
+// Блокировка не берётся: метод предназначен для проверок готовности.
+func CurrentVersion(ctx context.Context, db *sqlx.DB) (int64, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM "+schemaMigrationsTable).Scan(&version)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "undefined_table" {
+		// Мигратор ещё ни разу не запускался
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("CurrentVersion ошибка чтения версии схемы: %w", mapDBError(err))
+	}
+	return version.Int64, nil
+}
+
+// withLock выполняет fn на отдельном соединении под advisory-блокировкой.
+// Сессионная блокировка привязана к соединению, поэтому все запросы идут через conn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) (err error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("Migrator ошибка получения соединения: %w", mapDBError(err))
+	}
+	defer conn.Close() //nolint:errcheck
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("Migrator ошибка получения блокировки: %w", mapDBError(err))
+	}
+	defer func() {
+		// Контекст запроса мог быть отменён — блокировку снимаем в любом случае
+		if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); unlockErr != nil && err == nil {
+			err = fmt.Errorf("Migrator ошибка снятия блокировки: %w", mapDBError(unlockErr))
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+schemaMigrationsTable+` (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("Migrator ошибка создания таблицы %s: %w", schemaMigrationsTable, mapDBError(err))
+	}
+
+	return fn(conn)
+}
+
+// apply выполняет up- или down-часть миграции и обновляет schema_migrations в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration Migration, up bool) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Migrator ошибка начала транзакции: %w", mapDBError(err))
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	script, direction := migration.up, "up"
+	if !up {
+		script, direction = migration.down, "down"
+	}
+	// Скрипт выполняется без параметров, поэтому может содержать несколько команд
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("Migrator ошибка выполнения миграции %d_%s (%s): %w", migration.Version, migration.Name, direction, mapDBError(err))
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO "+schemaMigrationsTable+" (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+schemaMigrationsTable+" WHERE version = $1", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("Migrator ошибка записи версии %d: %w", migration.Version, mapDBError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Migrator ошибка фиксации миграции %d: %w", migration.Version, mapDBError(err))
+	}
+	return nil
+}
+
+// appliedVersions возвращает применённые версии и время их применения
+func appliedVersions(ctx context.Context, conn *sqlx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+schemaMigrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("Migrator ошибка чтения применённых миграций: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("Migrator ошибка сканирования строки: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Migrator ошибка итерации по строкам: %w", mapDBError(err))
+	}
+	return applied, nil
+}
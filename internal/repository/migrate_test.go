@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/repository/migrations"
+)
+
+func TestNewMigrator(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		want     []Migration
+		wantErr  bool
+		checkErr error
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"20260301120000_add_check.up.sql":   file("ALTER 2"),
+				"20260301120000_add_check.down.sql": file("UNDO 2"),
+				"20260226003955_create.up.sql":      file("CREATE 1"),
+				"20260226003955_create.down.sql":    file("DROP 1"),
+				"migrations.go":                     file("package migrations"),
+			},
+			want: []Migration{
+				{Version: 20260226003955, Name: "create", up: "CREATE 1", down: "DROP 1"},
+				{Version: 20260301120000, Name: "add_check", up: "ALTER 2", down: "UNDO 2"},
+			},
+		},
+		{
+			name:    "missing down file",
+			fsys:    fstest.MapFS{"20260226003955_create.up.sql": file("CREATE 1")},
+			wantErr: true,
+		},
+		{
+			name: "names differ between up and down",
+			fsys: fstest.MapFS{
+				"20260226003955_create.up.sql":    file("CREATE 1"),
+				"20260226003955_created.down.sql": file("DROP 1"),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "no migrations",
+			fsys:     fstest.MapFS{"README.md": file("docs")},
+			wantErr:  true,
+			checkErr: ErrNoMigrations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMigrator(nil, tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMigrator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.checkErr != nil && !errors.Is(err, tt.checkErr) {
+				t.Errorf("NewMigrator() error = %v, want %v", err, tt.checkErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(m.migrations, tt.want) {
+				t.Errorf("NewMigrator() migrations = %+v, want %+v", m.migrations, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// Каждая встроенная миграция должна иметь up- и down-файл с одинаковым названием
+	m, err := NewMigrator(nil, migrations.FS)
+	if err != nil {
+		t.Fatalf("NewMigrator(migrations.FS) error = %v", err)
+	}
+	if first := m.migrations[0]; first.Name != "create_subscription_table" {
+		t.Errorf("first migration = %d_%s, want create_subscription_table", first.Version, first.Name)
+	}
+}
@@ -1,3 +1,3 @@
-DROP INDEX IF EXISTS idx_subscription_user_id;
+DROP INDEX IF EXISTS idx_subscriptions_user_id;
 
-DROP TABLE IF EXISTS subscription;
\ No newline at end of file
+DROP TABLE IF EXISTS subscription;
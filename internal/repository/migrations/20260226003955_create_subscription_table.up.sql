@@ -7,5 +7,5 @@ CREATE TABLE IF NOT EXISTS subscription (
     end_date DATE
 );
 
-CREATE INDEX idx_subscriptions_user_id ON subscription (user_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscription (user_id);
 
@@ -0,0 +1 @@
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS chk_subscription_dates;
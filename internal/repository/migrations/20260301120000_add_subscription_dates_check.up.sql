@@ -0,0 +1,5 @@
+-- Дата окончания не может предшествовать дате начала.
+-- NOT VALID: ограничение проверяется для новых и изменяемых строк, существующие данные не блокируют миграцию.
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS chk_subscription_dates;
+ALTER TABLE subscription
+    ADD CONSTRAINT chk_subscription_dates CHECK (end_date IS NULL OR end_date >= start_date) NOT VALID;
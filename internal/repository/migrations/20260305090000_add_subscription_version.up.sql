@@ -0,0 +1,4 @@
+-- Версия записи для оптимистичной блокировки (ETag / If-Match)
+ALTER TABLE subscription
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
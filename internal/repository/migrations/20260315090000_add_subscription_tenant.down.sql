@@ -0,0 +1,8 @@
+DROP POLICY IF EXISTS subscription_all_tenants_read ON subscription;
+DROP POLICY IF EXISTS subscription_tenant_isolation ON subscription;
+ALTER TABLE subscription DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS idx_subscriptions_tenant_user_id;
+
+ALTER TABLE subscription
+    DROP COLUMN IF EXISTS tenant_id;
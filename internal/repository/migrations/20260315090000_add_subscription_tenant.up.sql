@@ -0,0 +1,22 @@
+-- Арендатор подписки. Существующие записи относятся к арендатору по умолчанию.
+ALTER TABLE subscription
+    ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user_id ON subscription (tenant_id, user_id);
+
+-- Row-Level Security — вторая линия защиты после условия tenant_id в запросах сервиса.
+-- Сервис выполняет запросы в транзакции с SET LOCAL app.tenant_id; без этой настройки
+-- строки не видны. Значение '*' открывает чтение всех арендаторов для служебных задач.
+-- Политики не действуют на владельца таблицы и суперпользователя, поэтому сервис
+-- должен подключаться отдельной ролью, а миграции — выполняться владельцем.
+ALTER TABLE subscription ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subscription_tenant_isolation ON subscription;
+CREATE POLICY subscription_tenant_isolation ON subscription
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY IF EXISTS subscription_all_tenants_read ON subscription;
+CREATE POLICY subscription_all_tenants_read ON subscription
+    FOR SELECT
+    USING (current_setting('app.tenant_id', true) = '*');
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_rate_limit_bucket_expires_at;
+
+DROP TABLE IF EXISTS rate_limit_bucket;
@@ -0,0 +1,10 @@
+-- Корзины ограничения частоты запросов, общие для всех экземпляров сервиса
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    -- Момент, когда корзина наполнится; после него запись можно удалить
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bucket_expires_at ON rate_limit_bucket (expires_at);
@@ -0,0 +1,8 @@
+DROP FUNCTION IF EXISTS subscription_charge_dates(DATE, DATE, TEXT, INT, INT, DATE, DATE);
+
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS subscription_billing_interval_check;
+
+ALTER TABLE subscription
+    DROP COLUMN IF EXISTS billing_anchor_day,
+    DROP COLUMN IF EXISTS billing_interval_count,
+    DROP COLUMN IF EXISTS billing_interval;
@@ -0,0 +1,67 @@
+-- Интервал оплаты подписки: каждые billing_interval_count единиц billing_interval.
+-- Существующие подписки оплачиваются ежемесячно, первого числа.
+ALTER TABLE subscription
+    ADD COLUMN IF NOT EXISTS billing_interval VARCHAR(8) NOT NULL DEFAULT 'month',
+    ADD COLUMN IF NOT EXISTS billing_interval_count INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS billing_anchor_day SMALLINT NOT NULL DEFAULT 1;
+
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS subscription_billing_interval_check;
+ALTER TABLE subscription ADD CONSTRAINT subscription_billing_interval_check
+    CHECK (billing_interval IN ('day', 'week', 'month', 'year')
+        AND billing_interval_count > 0
+        AND billing_anchor_day BETWEEN 1 AND 31);
+
+-- Даты списаний подписки в месяцах window_start..window_end (оба — первое число месяца,
+-- оба месяца входят в окно). Подписка действует с первого числа start_month до конца
+-- месяца end_month (NULL — бессрочно). Первое списание приходится на anchor_day месяца
+-- начала; для месячных и годовых интервалов день списания ограничивается длиной месяца,
+-- поэтому подписка с anchor_day = 31 списывается в последний день каждого месяца.
+CREATE OR REPLACE FUNCTION subscription_charge_dates(
+    start_month DATE,
+    end_month DATE,
+    interval_unit TEXT,
+    interval_count INT,
+    anchor_day INT,
+    window_start DATE,
+    window_end DATE
+) RETURNS SETOF DATE
+LANGUAGE plpgsql IMMUTABLE AS $$
+DECLARE
+    lo DATE := GREATEST(start_month, window_start);
+    hi DATE := (LEAST(COALESCE(end_month, window_end), window_end) + interval '1 month' - interval '1 day')::date;
+    step INT;
+    k INT;
+    charge_month DATE;
+    first_charge DATE;
+BEGIN
+    IF lo > hi THEN
+        RETURN;
+    END IF;
+
+    IF interval_unit IN ('month', 'year') THEN
+        step := interval_count * CASE interval_unit WHEN 'year' THEN 12 ELSE 1 END;
+        -- Первый период, месяц списания которого не раньше lo
+        k := ((EXTRACT(YEAR FROM lo) * 12 + EXTRACT(MONTH FROM lo))
+            - (EXTRACT(YEAR FROM start_month) * 12 + EXTRACT(MONTH FROM start_month)))::int;
+        k := (k + step - 1) / step;
+        LOOP
+            charge_month := (start_month + make_interval(months => k * step))::date;
+            EXIT WHEN charge_month > hi;
+            RETURN NEXT charge_month + LEAST(anchor_day,
+                EXTRACT(DAY FROM charge_month + interval '1 month' - interval '1 day')::int) - 1;
+            k := k + 1;
+        END LOOP;
+    ELSE
+        step := interval_count * CASE interval_unit WHEN 'week' THEN 7 ELSE 1 END;
+        first_charge := start_month + LEAST(anchor_day,
+            EXTRACT(DAY FROM start_month + interval '1 month' - interval '1 day')::int) - 1;
+        -- Первое списание не раньше lo
+        k := (GREATEST(lo - first_charge, 0) + step - 1) / step;
+        LOOP
+            EXIT WHEN first_charge + k * step > hi;
+            RETURN NEXT first_charge + k * step;
+            k := k + 1;
+        END LOOP;
+    END IF;
+END;
+$$;
@@ -0,0 +1,19 @@
+-- Изменения цены подписки. Цена действует с первого числа месяца effective_from
+-- до следующего изменения; до первого изменения действует subscription.price.
+CREATE TABLE IF NOT EXISTS subscription_price (
+    subscription_id UUID NOT NULL REFERENCES subscription (id) ON DELETE CASCADE,
+    effective_from DATE NOT NULL,
+    price BIGINT NOT NULL CHECK (price > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (subscription_id, effective_from),
+    CHECK (effective_from = date_trunc('month', effective_from)::date)
+);
+
+-- Изменения цены видны только вместе с подпиской: политика RLS подписки
+-- действует и внутри подзапроса
+ALTER TABLE subscription_price ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subscription_price_tenant_isolation ON subscription_price;
+CREATE POLICY subscription_price_tenant_isolation ON subscription_price
+    USING (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id));
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS subscription_promotion;
+
+DROP INDEX IF EXISTS idx_subscriptions_tenant_trial_end;
+
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS subscription_trial_end_check;
+
+ALTER TABLE subscription DROP COLUMN IF EXISTS trial_end;
@@ -0,0 +1,28 @@
+-- Пробный период: последний бесплатный месяц подписки. Списания начинаются
+-- со следующего месяца, по интервалу оплаты подписки.
+ALTER TABLE subscription ADD COLUMN IF NOT EXISTS trial_end DATE;
+
+ALTER TABLE subscription DROP CONSTRAINT IF EXISTS subscription_trial_end_check;
+ALTER TABLE subscription ADD CONSTRAINT subscription_trial_end_check
+    CHECK (trial_end IS NULL OR trial_end >= start_date);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_trial_end ON subscription (tenant_id, trial_end)
+    WHERE trial_end IS NOT NULL;
+
+-- Промо-периоды: в месяцах start_month..end_month (включительно) списывается
+-- цена price вместо обычной; price = 0 — бесплатные месяцы
+CREATE TABLE IF NOT EXISTS subscription_promotion (
+    subscription_id UUID NOT NULL REFERENCES subscription (id) ON DELETE CASCADE,
+    start_month DATE NOT NULL,
+    end_month DATE NOT NULL,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    PRIMARY KEY (subscription_id, start_month),
+    CHECK (end_month >= start_month)
+);
+
+ALTER TABLE subscription_promotion ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subscription_promotion_tenant_isolation ON subscription_promotion;
+CREATE POLICY subscription_promotion_tenant_isolation ON subscription_promotion
+    USING (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id));
@@ -0,0 +1,22 @@
+-- Приостановки подписок: месяцы start_month..end_month (включительно)
+-- не оплачиваются; end_month IS NULL — подписка приостановлена до возобновления.
+-- Пересечение приостановок одной подписки проверяет сервис.
+CREATE TABLE IF NOT EXISTS subscription_pause (
+    subscription_id UUID NOT NULL REFERENCES subscription (id) ON DELETE CASCADE,
+    start_month DATE NOT NULL,
+    end_month DATE,
+    PRIMARY KEY (subscription_id, start_month),
+    CHECK (start_month = date_trunc('month', start_month)::date),
+    CHECK (end_month IS NULL OR end_month >= start_month)
+);
+
+-- У подписки не больше одной бессрочной приостановки
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_pause_open ON subscription_pause (subscription_id)
+    WHERE end_month IS NULL;
+
+ALTER TABLE subscription_pause ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subscription_pause_tenant_isolation ON subscription_pause;
+CREATE POLICY subscription_pause_tenant_isolation ON subscription_pause
+    USING (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id));
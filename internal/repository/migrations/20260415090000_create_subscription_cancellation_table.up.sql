@@ -0,0 +1,30 @@
+-- Отмены подписок. effective_month — последний оплачиваемый месяц, при отмене
+-- он записывается в subscription.end_date, а прежняя дата окончания сохраняется
+-- в previous_end_date для возобновления. Возобновлённые отмены остаются в таблице
+-- с заполненным uncancelled_at и не входят в отчёт по причинам.
+CREATE TABLE IF NOT EXISTS subscription_cancellation (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscription (id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL CHECK (reason IN (
+        'too_expensive', 'not_using', 'switched_service', 'missing_features', 'technical_issues', 'other'
+    )),
+    note TEXT NOT NULL DEFAULT '',
+    effective_month DATE NOT NULL CHECK (effective_month = date_trunc('month', effective_month)::date),
+    previous_end_date DATE,
+    cancelled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    uncancelled_at TIMESTAMPTZ
+);
+
+-- У подписки не больше одной действующей отмены
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_cancellation_active ON subscription_cancellation (subscription_id)
+    WHERE uncancelled_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subscription_cancellation_effective_month ON subscription_cancellation (effective_month)
+    WHERE uncancelled_at IS NULL;
+
+ALTER TABLE subscription_cancellation ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subscription_cancellation_tenant_isolation ON subscription_cancellation;
+CREATE POLICY subscription_cancellation_tenant_isolation ON subscription_cancellation
+    USING (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM subscription s WHERE s.id = subscription_id));
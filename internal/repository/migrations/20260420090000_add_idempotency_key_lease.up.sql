@@ -0,0 +1,6 @@
+-- Аренда ключа выполняющимся запросом: если запрос не сохранил ответ до
+-- locked_until (сбой, разрыв соединения), ключ может занять повтор
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+UPDATE idempotency_key SET locked_until = created_at + interval '1 minute'
+    WHERE status_code IS NULL AND locked_until IS NULL;
@@ -0,0 +1,23 @@
+-- Роль subscription_app общая для кластера и может использоваться другими базами,
+-- поэтому откат только отзывает её права
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM subscription_app;
+
+REVOKE SELECT ON schema_migrations FROM subscription_app;
+REVOKE EXECUTE ON FUNCTION subscription_charge_dates FROM subscription_app;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON
+    subscription,
+    subscription_price,
+    subscription_promotion,
+    subscription_pause,
+    subscription_cancellation,
+    idempotency_key,
+    rate_limit_bucket
+FROM subscription_app;
+REVOKE USAGE ON SCHEMA public FROM subscription_app;
+
+ALTER TABLE subscription_cancellation NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pause NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_promotion NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_price NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription NO FORCE ROW LEVEL SECURITY;
@@ -0,0 +1,37 @@
+-- Политики RLS действуют и на владельца таблиц: без FORCE сервис, подключённый
+-- владельцем, видел бы строки всех арендаторов. Суперпользователь RLS обходит всегда,
+-- поэтому сервис подключается ролью, входящей в subscription_app.
+ALTER TABLE subscription FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_price FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_promotion FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_pause FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_cancellation FORCE ROW LEVEL SECURITY;
+
+-- Групповая роль сервиса: права только на данные, без владения таблицами и без
+-- BYPASSRLS. Роль с правом входа создаётся при развёртывании и включается в группу:
+-- GRANT subscription_app TO <роль сервиса>.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'subscription_app') THEN
+        CREATE ROLE subscription_app NOLOGIN NOSUPERUSER NOBYPASSRLS;
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO subscription_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON
+    subscription,
+    subscription_price,
+    subscription_promotion,
+    subscription_pause,
+    subscription_cancellation,
+    idempotency_key,
+    rate_limit_bucket
+TO subscription_app;
+GRANT EXECUTE ON FUNCTION subscription_charge_dates TO subscription_app;
+-- Версию схемы сервис показывает в /readyz
+GRANT SELECT ON schema_migrations TO subscription_app;
+
+-- Таблицы следующих миграций получают те же права
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO subscription_app;
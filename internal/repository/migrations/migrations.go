@@ -0,0 +1,12 @@
+// Package migrations содержит SQL-миграции схемы базы данных, встроенные в бинарный файл.
+//
+// Файлы именуются как <версия>_<название>.up.sql и <версия>_<название>.down.sql;
+// версия — метка времени создания миграции, миграции применяются в порядке версий.
+// Миграции пишутся идемпотентными (IF [NOT] EXISTS), чтобы их можно было применить
+// к базе, созданной до появления таблицы schema_migrations.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,23 +1,103 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/BountyM/effectiveMobileTestTask/internal/config"
 	"github.com/jmoiron/sqlx"
 )
 
-func NewPostgresDB(cfg config.DB) (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.Dbname, cfg.Password, cfg.Sslmode))
+// maxConnectBackoff ограничивает паузу между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
+// NewPostgresDB открывает пул соединений и дожидается доступности базы:
+// при старте вместе с базой (например, в docker compose) она может ещё не принимать соединения.
+func NewPostgresDB(ctx context.Context, cfg config.DB, log *slog.Logger) (*sqlx.DB, error) {
+	db, err := sqlx.Open("postgres", postgresDSN(cfg))
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+	attempts := max(cfg.ConnectAttempts, 1)
+	backoff := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err = ping(ctx, db, cfg.StatementTimeout)
+		if err == nil {
+			return db, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		log.Warn("database is not ready, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			db.Close() //nolint:errcheck
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	db.Close() //nolint:errcheck
+	return nil, fmt.Errorf("database is not available after %d attempts: %w", attempts, err)
+}
+
+// postgresDSN собирает строку подключения в виде URL: имя пользователя, пароль
+// и параметры экранируются, поэтому пробелы, кавычки и @ в них допустимы.
+func postgresDSN(cfg config.DB) string {
+	query := url.Values{}
+	query.Set("sslmode", cfg.Sslmode)
+	if cfg.SSLRootCert != "" {
+		query.Set("sslrootcert", cfg.SSLRootCert)
+	}
+	if cfg.SSLCert != "" {
+		query.Set("sslcert", cfg.SSLCert)
+	}
+	if cfg.SSLKey != "" {
+		query.Set("sslkey", cfg.SSLKey)
+	}
+	if cfg.ApplicationName != "" {
+		query.Set("application_name", cfg.ApplicationName)
+	}
+	if cfg.ConnectTimeout > 0 {
+		// Postgres принимает таймаут в целых секундах; меньше 2 секунд libpq не допускает
+		seconds := int64(math.Ceil(cfg.ConnectTimeout.Seconds()))
+		query.Set("connect_timeout", strconv.FormatInt(max(seconds, 2), 10))
 	}
 
-	return db, nil
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     "/" + cfg.Dbname,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+func ping(ctx context.Context, db *sqlx.DB, timeout time.Duration) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+// withTimeout ограничивает время выполнения запроса. Если у ctx уже есть более
+// ранний дедлайн, действует он; нулевой timeout ограничение не добавляет.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+)
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       time.Duration // 0 — родительский контекст без дедлайна
+		timeout      time.Duration
+		wantDeadline bool
+		wantAtMost   time.Duration
+	}{
+		{name: "no timeout", wantDeadline: false},
+		{name: "negative timeout", timeout: -time.Second, wantDeadline: false},
+		{name: "timeout applied", timeout: time.Second, wantDeadline: true, wantAtMost: time.Second},
+		{name: "earlier parent deadline wins", parent: 100 * time.Millisecond, timeout: time.Minute, wantDeadline: true, wantAtMost: 100 * time.Millisecond},
+		{name: "earlier timeout wins", parent: time.Minute, timeout: time.Second, wantDeadline: true, wantAtMost: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			if tt.parent > 0 {
+				var cancel context.CancelFunc
+				parent, cancel = context.WithTimeout(parent, tt.parent)
+				defer cancel()
+			}
+
+			ctx, cancel := withTimeout(parent, tt.timeout)
+			deadline, ok := ctx.Deadline()
+			if ok != tt.wantDeadline {
+				t.Fatalf("withTimeout() deadline set = %v, want %v", ok, tt.wantDeadline)
+			}
+			if ok && time.Until(deadline) > tt.wantAtMost {
+				t.Errorf("withTimeout() deadline in %v, want at most %v", time.Until(deadline), tt.wantAtMost)
+			}
+
+			// Отмена освобождает контекст и без дедлайна
+			cancel()
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				t.Errorf("ctx.Err() after cancel = %v, want context.Canceled", ctx.Err())
+			}
+		})
+	}
+}
+
+func TestNewPostgresDBUnavailable(t *testing.T) {
+	// На порту 1 никто не слушает, поэтому каждая попытка подключения завершается ошибкой
+	cfg := config.DB{Host: "127.0.0.1", Port: "1", Username: "app", Dbname: "app", Sslmode: "disable", ConnectTimeout: 2 * time.Second}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		attempts int
+		backoff  time.Duration
+		cancel   bool
+		wantErr  error
+	}{
+		{name: "attempts exhausted", attempts: 2, backoff: time.Millisecond},
+		// Ожидание между попытками прерывается отменой контекста запуска
+		{name: "cancelled while waiting", attempts: 5, backoff: time.Hour, cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			cfg.ConnectAttempts, cfg.ConnectBackoff = tt.attempts, tt.backoff
+			db, err := NewPostgresDB(ctx, cfg, logger)
+			if err == nil {
+				db.Close() //nolint:errcheck
+				t.Fatal("NewPostgresDB() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewPostgresDB() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	base := config.DB{Host: "db", Port: "5432", Username: "app", Password: "secret", Dbname: "subscription", Sslmode: "disable"}
+	with := func(change func(cfg *config.DB)) config.DB {
+		cfg := base
+		change(&cfg)
+		return cfg
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.DB
+		want string
+	}{
+		{name: "minimal", cfg: base, want: "postgres://app:secret@db:5432/subscription?sslmode=disable"},
+		{
+			name: "credentials are escaped",
+			cfg:  with(func(cfg *config.DB) { cfg.Username, cfg.Password = "app user", "p@ss w'rd" }),
+			want: "postgres://app%20user:p%40ss%20w%27rd@db:5432/subscription?sslmode=disable",
+		},
+		{name: "IPv6 host", cfg: with(func(cfg *config.DB) { cfg.Host = "::1" }), want: "postgres://app:secret@[::1]:5432/subscription?sslmode=disable"},
+		{
+			name: "TLS and application name",
+			cfg: with(func(cfg *config.DB) {
+				cfg.Sslmode, cfg.SSLRootCert, cfg.SSLCert, cfg.SSLKey = "verify-full", "/certs/ca.pem", "/certs/client.pem", "/certs/client.key"
+				cfg.ApplicationName = "subscription api"
+			}),
+			want: "postgres://app:secret@db:5432/subscription?application_name=subscription+api&sslcert=%2Fcerts%2Fclient.pem" +
+				"&sslkey=%2Fcerts%2Fclient.key&sslmode=verify-full&sslrootcert=%2Fcerts%2Fca.pem",
+		},
+		// Таймаут округляется вверх до секунд, но не меньше 2 секунд
+		{name: "connect timeout rounded up", cfg: with(func(cfg *config.DB) { cfg.ConnectTimeout = 2500 * time.Millisecond }), want: "postgres://app:secret@db:5432/subscription?connect_timeout=3&sslmode=disable"},
+		{name: "connect timeout minimum", cfg: with(func(cfg *config.DB) { cfg.ConnectTimeout = time.Second }), want: "postgres://app:secret@db:5432/subscription?connect_timeout=2&sslmode=disable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postgresDSN(tt.cfg); got != tt.want {
+				t.Errorf("postgresDSN() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/ratelimit"
+	"github.com/jmoiron/sqlx"
+)
+
+const rateLimitBucketTable = "rate_limit_bucket"
+
+// RateLimitPostgres хранит корзины ограничения частоты запросов в Postgres,
+// чтобы лимит действовал на все экземпляры сервиса
+type RateLimitPostgres struct {
+	db      tracedDB
+	timeout time.Duration
+}
+
+func NewRateLimitPostgres(db *sqlx.DB, timeout time.Duration) *RateLimitPostgres {
+	return &RateLimitPostgres{
+		db:      tracedDB{db},
+		timeout: timeout,
+	}
+}
+
+// Take берёт токен из корзины key. Строка корзины блокируется до конца транзакции,
+// поэтому параллельные запросы с одним ключом расходуют токены по очереди.
+func (r *RateLimitPostgres) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("RateLimitPostgres Take() ошибка начала транзакции: %w", mapDBError(err))
+	}
+	defer tx.Rollback() //nolint:errcheck
+	q := tracedTx{tx}
+
+	// Новая корзина создаётся полной; у существующей пустое обновление
+	// блокирует строку и возвращает её текущее состояние
+	var bucket ratelimit.Bucket
+	err = q.QueryRowContext(ctx,
+		"INSERT INTO "+rateLimitBucketTable+" AS b (key, tokens, updated_at, expires_at) VALUES ($1, $2, $3, $3) "+
+			"ON CONFLICT (key) DO UPDATE SET key = b.key RETURNING b.tokens, b.updated_at",
+		key, float64(limit.Burst), now).Scan(&bucket.Tokens, &bucket.Updated)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("RateLimitPostgres Take() ошибка чтения корзины: %w", mapDBError(err))
+	}
+
+	bucket, res := bucket.Take(limit, now)
+	_, err = q.ExecContext(ctx,
+		"UPDATE "+rateLimitBucketTable+" SET tokens = $2, updated_at = $3, expires_at = $4 WHERE key = $1",
+		key, bucket.Tokens, bucket.Updated, now.Add(res.Reset))
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("RateLimitPostgres Take() ошибка сохранения корзины: %w", mapDBError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("RateLimitPostgres Take() ошибка фиксации транзакции: %w", mapDBError(err))
+	}
+	return res, nil
+}
+
+// DeleteExpired удаляет наполнившиеся корзины: они не отличаются от новых
+func (r *RateLimitPostgres) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM "+rateLimitBucketTable+" WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("RateLimitPostgres DeleteExpired() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("RateLimitPostgres DeleteExpired() ошибка получения количества изменённых строк: %w", err)
+	}
+	return deleted, nil
+}
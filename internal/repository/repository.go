@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
 	"github.com/jmoiron/sqlx"
 )
 
 type Repository struct {
 	Subscription
+	Idempotency Idempotency
+	Health      Health
 }
 
-func New(db *sqlx.DB) *Repository {
+func New(db *sqlx.DB, cfg config.DB) *Repository {
 	return &Repository{
-		Subscription: NewSubscriptionPostgres(db),
+		Subscription: NewSubscriptionPostgres(db, cfg.StatementTimeout),
+		Idempotency:  NewIdempotencyPostgres(db, cfg.StatementTimeout),
+		Health:       NewHealthPostgres(db),
 	}
 }
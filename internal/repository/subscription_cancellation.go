@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+const subscriptionCancellationTable = "subscription_cancellation"
+
+// Cancel отменяет подписку: последним оплачиваемым месяцем становится текущий месяц,
+// месяц перед следующим списанием после текущего месяца или заданный месяц.
+// Этот месяц записывается в end_date подписки. Повторная отмена, отмена
+// закончившейся подписки и отмена ещё не начавшейся подписки без заданного месяца
+// возвращают ErrConflict.
+func (r *SubscriptionPostgres) Cancel(ctx context.Context, id uuid.UUID, req models.CancelRequest, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	startDate, endDate, err := touch(ctx, tx, "Cancel", id, ifVersions)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	var cancelled bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM "+subscriptionCancellationTable+" WHERE subscription_id = $1 AND uncancelled_at IS NULL)",
+		id).Scan(&cancelled)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() ошибка проверки отмены: %w", mapDBError(err))
+	}
+	if cancelled {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() подписка %s уже отменена: %w", id, models.ErrConflict)
+	}
+
+	// Конец оплаченного периода — месяц перед первым списанием после текущего месяца.
+	// Окно поиска в billing_interval_count лет вмещает любой интервал оплаты.
+	var thisMonth, periodEnd time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT m.this_month, LEAST(COALESCE((SELECT (date_trunc('month', MIN(c.charge_date)) - interval '1 month')::date "+
+			"FROM subscription_charge_dates(COALESCE((s.trial_end + interval '1 month')::date, s.start_date), s.end_date, "+
+			"s.billing_interval, s.billing_interval_count, s.billing_anchor_day, "+
+			"(m.this_month + interval '1 month')::date, (m.this_month + make_interval(months => 1, years => s.billing_interval_count))::date"+
+			") AS c(charge_date)), s.end_date, m.this_month), s.end_date) "+
+			"FROM "+models.SubscriptionTable+" s, (SELECT date_trunc('month', CURRENT_DATE)::date AS this_month) m "+
+			"WHERE s.id = $1 AND s.tenant_id = $2",
+		id, tx.tenant).Scan(&thisMonth, &periodEnd)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() ошибка расчёта конца периода: %w", mapDBError(err))
+	}
+	if endDate != nil && endDate.Before(thisMonth) {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() подписка %s уже закончилась: %w", id, models.ErrConflict)
+	}
+
+	// Для ещё не начавшейся подписки нет ни текущего, ни оплаченного периода:
+	// её удаляют или отменяют с явным месяцем
+	if startDate.After(thisMonth) && req.When != models.CancelAtMonth {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() подписка %s ещё не началась: %w", id,
+			models.NewConflictError("subscription has not started yet, delete it or cancel it with effective_month"))
+	}
+
+	var effective time.Time
+	switch req.When {
+	case models.CancelImmediately:
+		effective = thisMonth
+	case models.CancelEndOfPeriod:
+		effective = periodEnd
+	default:
+		effective = req.Month
+	}
+	var verr models.ValidationError
+	if effective.Before(thisMonth) {
+		verr.Add("effective_month", "effective_month must not be in the past")
+	}
+	if effective.Before(startDate) {
+		verr.Add("effective_month", "effective_month must not be before start_date")
+	}
+	if endDate != nil && effective.After(*endDate) {
+		verr.Add("effective_month", "effective_month must not be after end_date")
+	}
+	if err := verr.OrNil(); err != nil {
+		return models.Subscription{}, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO "+subscriptionCancellationTable+
+			" (id, subscription_id, reason, note, effective_month, previous_end_date) VALUES ($1, $2, $3, $4, $5, $6)",
+		uuid.New(), id, req.Reason, req.Note, effective, endDate)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() ошибка записи отмены: %w", mapDBError(err))
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE "+models.SubscriptionTable+" SET end_date = $3 WHERE id = $1 AND tenant_id = $2", id, tx.tenant, effective)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() ошибка изменения даты окончания: %w", mapDBError(err))
+	}
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Cancel() %w", err)
+	}
+	return sub, nil
+}
+
+// Uncancel отзывает действующую отмену подписки и восстанавливает прежнюю дату окончания.
+// Отмену можно отозвать, пока не прошёл её последний оплачиваемый месяц; иначе,
+// как и для неотменённой подписки, возвращается ErrConflict.
+func (r *SubscriptionPostgres) Uncancel(ctx context.Context, id uuid.UUID, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, _, err := touch(ctx, tx, "Uncancel", id, ifVersions); err != nil {
+		return models.Subscription{}, err
+	}
+
+	var (
+		cancellationID  uuid.UUID
+		previousEndDate *time.Time
+		pending         bool
+	)
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, previous_end_date, effective_month >= date_trunc('month', CURRENT_DATE) FROM "+subscriptionCancellationTable+
+			" WHERE subscription_id = $1 AND uncancelled_at IS NULL",
+		id).Scan(&cancellationID, &previousEndDate, &pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() подписка %s не отменена: %w", id, models.ErrConflict)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() ошибка чтения отмены: %w", mapDBError(err))
+	}
+	if !pending {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() отмена подписки %s уже вступила в силу: %w", id, models.ErrConflict)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE "+subscriptionCancellationTable+" SET uncancelled_at = now() WHERE id = $1", cancellationID)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() ошибка отзыва отмены: %w", mapDBError(err))
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE "+models.SubscriptionTable+" SET end_date = $3 WHERE id = $1 AND tenant_id = $2", id, tx.tenant, previousEndDate)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() ошибка изменения даты окончания: %w", mapDBError(err))
+	}
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Uncancel() %w", err)
+	}
+	return sub, nil
+}
+
+// CancellationReasons возвращает число действующих отмен по причинам, последний
+// оплачиваемый месяц которых попадает в период params.StartDate..params.EndDate,
+// и цену отменённых подписок в пересчёте на месяц на этот месяц.
+func (r *SubscriptionPostgres) CancellationReasons(ctx context.Context, params models.SubscriptionParams) ([]models.CancellationReasonStats, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := squirrel.Select("c.reason", "COUNT(*)",
+		"COALESCE(ROUND(SUM("+monthlyPriceSQL(priceAt("s", "c.effective_month"))+")), 0)::bigint").
+		From(subscriptionCancellationTable+" AS c").
+		Join(models.SubscriptionTable+" AS s ON s.id = c.subscription_id").
+		Where("c.uncancelled_at IS NULL").
+		Where(squirrel.Eq{"s.tenant_id": tx.tenant}).
+		Where(squirrel.GtOrEq{"c.effective_month": params.StartDate}).
+		Where(squirrel.LtOrEq{"c.effective_month": params.EndDate}).
+		GroupBy("c.reason").
+		OrderBy("COUNT(*) DESC", "c.reason").
+		PlaceholderFormat(squirrel.Dollar)
+
+	if params.UserID != nil {
+		query = query.Where(squirrel.Eq{"s.user_id": *params.UserID})
+	}
+	if params.ServiceName != "" {
+		query = query.Where(squirrel.Eq{"s.service_name": params.ServiceName})
+	}
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() ошибка построения SQL-запроса: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var stats []models.CancellationReasonStats
+	for rows.Next() {
+		var reason models.CancellationReasonStats
+		if err := rows.Scan(&reason.Reason, &reason.Cancellations, &reason.LostMonthlySpend); err != nil {
+			return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() ошибка сканирования строки: %w", err)
+		}
+		stats = append(stats, reason)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() ошибка итерации по строкам: %w", mapDBError(err))
+	}
+	if err := tx.commit(); err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres CancellationReasons() %w", err)
+	}
+	return stats, nil
+}
+
+// lockEndDate блокирует подписку id до конца транзакции и проверяет, что endDate можно
+// записать в её дату окончания. Дату окончания отменённой подписки задаёт отмена:
+// другое значение отклоняется с ConflictError, пока отмена не отозвана. Если подписки
+// нет, проверка пропускается — отсутствие записи сообщит само изменение.
+func lockEndDate(ctx context.Context, tx *tenantTx, id uuid.UUID, endDate *time.Time) error {
+	sqlQuery, args, err := squirrel.Select("end_date",
+		"EXISTS(SELECT 1 FROM "+subscriptionCancellationTable+" c WHERE c.subscription_id = "+models.SubscriptionTable+".id AND c.uncancelled_at IS NULL)").
+		From(models.SubscriptionTable).
+		Where(tx.subscriptionKey(id)).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("ошибка построения SQL-запроса: %w", err)
+	}
+
+	var (
+		current   *time.Time
+		cancelled bool
+	)
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&current, &cancelled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка проверки отмены: %w", mapDBError(err))
+	}
+
+	unchanged := current == nil && endDate == nil || current != nil && endDate != nil && current.Equal(*endDate)
+	if cancelled && !unchanged {
+		return models.NewConflictError("subscription is cancelled, uncancel it before changing end_date")
+	}
+	return nil
+}
+
+// activeCancellation возвращает действующую отмену подписки id или nil
+func activeCancellation(ctx context.Context, tx *tenantTx, id uuid.UUID) (*models.Cancellation, error) {
+	var cancellation models.Cancellation
+	err := tx.QueryRowContext(ctx,
+		"SELECT reason, note, effective_month, cancelled_at FROM "+subscriptionCancellationTable+
+			" WHERE subscription_id = $1 AND uncancelled_at IS NULL", id).
+		Scan(&cancellation.Reason, &cancellation.Note, &cancellation.EffectiveMonth, &cancellation.CancelledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения отмены: %w", mapDBError(err))
+	}
+	return &cancellation, nil
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+const subscriptionPauseTable = "subscription_pause"
+
+// pausedAt возвращает условие «месяц даты at входит в приостановку подписки из таблицы table»
+func pausedAt(table, at string) string {
+	return "EXISTS (SELECT 1 FROM " + subscriptionPauseTable + " pa WHERE pa.subscription_id = " + table + ".id" +
+		" AND pa.start_month <= " + at + " AND (pa.end_month IS NULL OR pa.end_month >= date_trunc('month', " + at + ")))"
+}
+
+// chargeMonthsSQL — число месяцев, оплачиваемых одним списанием подписки s.
+// Интервалы короче месяца оплачивают месяц списания.
+var chargeMonthsSQL = "CASE s.billing_interval" +
+	" WHEN '" + models.BillingMonth + "' THEN s.billing_interval_count" +
+	" WHEN '" + models.BillingYear + "' THEN 12 * s.billing_interval_count" +
+	" ELSE 1 END"
+
+// unpausedShareSQL возвращает долю месяцев, оплачиваемых списанием подписки s
+// на дату at, которые не входят в приостановки
+func unpausedShareSQL(at string) string {
+	return "(SELECT count(*) FILTER (WHERE NOT " + pausedAt("s", "m.month") + ")::numeric / count(*)" +
+		" FROM generate_series(date_trunc('month', " + at + "), date_trunc('month', " + at + ")" +
+		" + (" + chargeMonthsSQL + " - 1) * interval '1 month', interval '1 month') AS m(month))"
+}
+
+// stateSQL — состояние подписки в текущем месяце
+var stateSQL = "CASE WHEN start_date > CURRENT_DATE THEN '" + models.StateScheduled + "'" +
+	" WHEN end_date < date_trunc('month', CURRENT_DATE) THEN '" + models.StateEnded + "'" +
+	" WHEN " + pausedAt(models.SubscriptionTable, "CURRENT_DATE") + " THEN '" + models.StatePaused + "'" +
+	" ELSE '" + models.StateActive + "' END"
+
+// Pause приостанавливает подписку на месяцы pause.StartMonth..pause.EndMonth
+// (без EndMonth — до возобновления). Приостановка не может пересекаться с уже
+// записанными; в этом случае возвращается ErrConflict.
+func (r *SubscriptionPostgres) Pause(ctx context.Context, id uuid.UUID, pause models.Pause, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	startDate, endDate, err := touch(ctx, tx, "Pause", id, ifVersions)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	var verr models.ValidationError
+	if pause.StartMonth.Before(startDate) {
+		verr.Add("start_month", "start_month must not be before start_date")
+	}
+	if endDate != nil && pause.StartMonth.After(*endDate) {
+		verr.Add("start_month", "start_month must not be after end_date")
+	}
+	if err := verr.OrNil(); err != nil {
+		return models.Subscription{}, err
+	}
+
+	var overlaps bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM "+subscriptionPauseTable+" WHERE subscription_id = $1"+
+			" AND (end_month IS NULL OR end_month >= $2) AND ($3::date IS NULL OR start_month <= $3))",
+		id, pause.StartMonth, pause.EndMonth).Scan(&overlaps)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() ошибка проверки приостановок: %w", mapDBError(err))
+	}
+	if overlaps {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() подписка %s уже приостановлена в этом периоде: %w", id, models.ErrConflict)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO "+subscriptionPauseTable+" (subscription_id, start_month, end_month) VALUES ($1, $2, $3)",
+		id, pause.StartMonth, pause.EndMonth)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() ошибка записи приостановки: %w", mapDBError(err))
+	}
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Pause() %w", err)
+	}
+	return sub, nil
+}
+
+// Resume возобновляет подписку с месяца month: приостановка, в которую входит month,
+// заканчивается предыдущим месяцем, а начинающаяся с month удаляется. Если month
+// не входит ни в одну приостановку, возвращается ErrConflict.
+func (r *SubscriptionPostgres) Resume(ctx context.Context, id uuid.UUID, month time.Time, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, _, err := touch(ctx, tx, "Resume", id, ifVersions); err != nil {
+		return models.Subscription{}, err
+	}
+
+	var pauseStart time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT start_month FROM "+subscriptionPauseTable+" WHERE subscription_id = $1"+
+			" AND start_month <= $2 AND (end_month IS NULL OR end_month >= $2)",
+		id, month).Scan(&pauseStart)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() подписка %s не приостановлена в этом месяце: %w", id, models.ErrConflict)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() ошибка чтения приостановки: %w", mapDBError(err))
+	}
+
+	if pauseStart.Equal(month) {
+		_, err = tx.ExecContext(ctx,
+			"DELETE FROM "+subscriptionPauseTable+" WHERE subscription_id = $1 AND start_month = $2", id, pauseStart)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE "+subscriptionPauseTable+" SET end_month = $3 WHERE subscription_id = $1 AND start_month = $2",
+			id, pauseStart, month.AddDate(0, -1, 0))
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() ошибка изменения приостановки: %w", mapDBError(err))
+	}
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Resume() %w", err)
+	}
+	return sub, nil
+}
+
+// CancelPause удаляет запланированную приостановку, начинающуюся с месяца startMonth.
+// Начавшуюся приостановку завершает Resume: для неё возвращается ErrConflict,
+// для отсутствующей — ErrNotFound.
+func (r *SubscriptionPostgres) CancelPause(ctx context.Context, id uuid.UUID, startMonth time.Time, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, _, err := touch(ctx, tx, "CancelPause", id, ifVersions); err != nil {
+		return models.Subscription{}, err
+	}
+
+	var scheduled bool
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM "+subscriptionPauseTable+" WHERE subscription_id = $1 AND start_month = $2"+
+			" RETURNING start_month > CURRENT_DATE", id, startMonth).Scan(&scheduled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() приостановка подписки %s с %s не найдена: %w",
+			id, startMonth.Format("01-2006"), models.ErrNotFound)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() ошибка удаления приостановки: %w", mapDBError(err))
+	}
+	// Удаление начавшейся приостановки откатывается вместе с транзакцией
+	if !scheduled {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() приостановка подписки %s уже началась: %w", id, models.ErrConflict)
+	}
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres CancelPause() %w", err)
+	}
+	return sub, nil
+}
+
+// touch увеличивает версию подписки id перед изменением связанных с ней записей;
+// строка подписки остаётся заблокированной до конца транзакции, поэтому параллельные
+// изменения выполняются по очереди. Возвращает даты начала и окончания подписки.
+func touch(ctx context.Context, tx *tenantTx, method string, id uuid.UUID, ifVersions []int64) (time.Time, *time.Time, error) {
+	builder := squirrel.Update(models.SubscriptionTable).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where(tx.subscriptionKey(id)).
+		Suffix("RETURNING start_date, end_date").
+		PlaceholderFormat(squirrel.Dollar)
+
+	if len(ifVersions) > 0 {
+		builder = builder.Where(squirrel.Eq{"version": ifVersions})
+	}
+
+	sqlQuery, args, err := builder.ToSql()
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("SubscriptionPostgres %s() ошибка построения SQL-запроса: %w", method, err)
+	}
+
+	var (
+		startDate time.Time
+		endDate   *time.Time
+	)
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&startDate, &endDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil, notAffectedError(ctx, tx, method, id, ifVersions)
+	}
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("SubscriptionPostgres %s() ошибка выполнения запроса: %w", method, mapDBError(err))
+	}
+	return startDate, endDate, nil
+}
+
+// pauses возвращает приостановки подписки id по возрастанию начала
+func pauses(ctx context.Context, tx *tenantTx, id uuid.UUID) ([]models.Pause, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT start_month, end_month FROM "+subscriptionPauseTable+
+			" WHERE subscription_id = $1 ORDER BY start_month", id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения приостановок: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var result []models.Pause
+	for rows.Next() {
+		var pause models.Pause
+		if err := rows.Scan(&pause.StartMonth, &pause.EndMonth); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования приостановки: %w", err)
+		}
+		result = append(result, pause)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения приостановок: %w", mapDBError(err))
+	}
+	return result, nil
+}
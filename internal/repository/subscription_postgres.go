@@ -1,7 +1,15 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BountyM/effectiveMobileTestTask/internal/models"
 	"github.com/Masterminds/squirrel"
@@ -10,24 +18,47 @@ import (
 )
 
 type Subscription interface {
-	Create(subscription models.Subscription) (uuid.UUID, error)
-	Get(params models.SubscriptionParams) ([]models.Subscription, error)
-	Delete(uuid.UUID) error
-	Update(uuid uuid.UUID, subscription models.Subscription) error
-	GetCost(params models.SubscriptionParams) (int64, error)
+	Create(ctx context.Context, subscription models.Subscription) (uuid.UUID, error)
+	Get(ctx context.Context, params models.SubscriptionParams) ([]models.Subscription, error)
+	GetByID(ctx context.Context, id uuid.UUID) (models.Subscription, error)
+	Count(ctx context.Context, params models.SubscriptionParams) (int64, error)
+	Delete(ctx context.Context, id uuid.UUID, ifVersions []int64) error
+	Update(ctx context.Context, id uuid.UUID, subscription models.Subscription, ifVersions []int64) (models.Subscription, error)
+	Patch(ctx context.Context, id uuid.UUID, patch models.SubscriptionPatch, ifVersions []int64) (models.Subscription, error)
+	SetPrice(ctx context.Context, id uuid.UUID, change models.PriceChange, ifVersions []int64) (models.Subscription, error)
+	Pause(ctx context.Context, id uuid.UUID, pause models.Pause, ifVersions []int64) (models.Subscription, error)
+	Resume(ctx context.Context, id uuid.UUID, month time.Time, ifVersions []int64) (models.Subscription, error)
+	CancelPause(ctx context.Context, id uuid.UUID, startMonth time.Time, ifVersions []int64) (models.Subscription, error)
+	Cancel(ctx context.Context, id uuid.UUID, req models.CancelRequest, ifVersions []int64) (models.Subscription, error)
+	Uncancel(ctx context.Context, id uuid.UUID, ifVersions []int64) (models.Subscription, error)
+	CancellationReasons(ctx context.Context, params models.SubscriptionParams) ([]models.CancellationReasonStats, error)
+	GetCost(ctx context.Context, params models.SubscriptionParams) (int64, error)
+	GetCostBreakdown(ctx context.Context, params models.SubscriptionParams) ([]models.CostBucket, error)
+	Stats(ctx context.Context, month time.Time) (models.SubscriptionStats, error)
 }
 
 type SubscriptionPostgres struct {
-	db *sqlx.DB
+	db      tracedDB
+	timeout time.Duration
 }
 
-func NewSubscriptionPostgres(db *sqlx.DB) *SubscriptionPostgres {
+func NewSubscriptionPostgres(db *sqlx.DB, timeout time.Duration) *SubscriptionPostgres {
 	return &SubscriptionPostgres{
-		db: db,
+		db:      tracedDB{db},
+		timeout: timeout,
 	}
 }
 
-func (r *SubscriptionPostgres) Create(subscription models.Subscription) (uuid.UUID, error) {
+func (r *SubscriptionPostgres) Create(ctx context.Context, subscription models.Subscription) (uuid.UUID, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("SubscriptionPostgres Create() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	builder := squirrel.Insert(models.SubscriptionTable).
 		Columns(
 			"id",
@@ -36,6 +67,11 @@ func (r *SubscriptionPostgres) Create(subscription models.Subscription) (uuid.UU
 			"user_id",
 			"start_date",
 			"end_date",
+			"billing_interval",
+			"billing_interval_count",
+			"billing_anchor_day",
+			"trial_end",
+			"tenant_id",
 		)
 	id := uuid.New()
 	// Подготавливаем значения
@@ -53,6 +89,8 @@ func (r *SubscriptionPostgres) Create(subscription models.Subscription) (uuid.UU
 	} else {
 		values = append(values, nil)
 	}
+	values = append(values, subscription.BillingInterval, subscription.BillingIntervalCount, subscription.BillingAnchorDay,
+		subscription.TrialEnd, tx.tenant)
 
 	query, args, err := builder.Values(values...).
 		PlaceholderFormat(squirrel.Dollar).
@@ -62,28 +100,60 @@ func (r *SubscriptionPostgres) Create(subscription models.Subscription) (uuid.UU
 	}
 
 	// Выполнение запроса
-	_, err = r.db.Exec(query, args...)
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("SubscriptionPostgres Create() ошибка выполнения SQL-запроса: %w", err)
+		return uuid.Nil, fmt.Errorf("SubscriptionPostgres Create() ошибка выполнения SQL-запроса: %w", mapDBError(err))
+	}
+	if err := replacePromotions(ctx, tx, id, subscription.Promotions); err != nil {
+		return uuid.Nil, fmt.Errorf("SubscriptionPostgres Create() %w", err)
+	}
+	if err := tx.commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("SubscriptionPostgres Create() %w", err)
 	}
 	return id, nil
 }
 
-func (r *SubscriptionPostgres) Get(params models.SubscriptionParams) ([]models.Subscription, error) {
-	query := squirrel.Select(
-		"id", "service_name", "price", "user_id", "start_date", "end_date").
+func (r *SubscriptionPostgres) Get(ctx context.Context, params models.SubscriptionParams) ([]models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres Get() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := squirrel.Select(subscriptionColumns...).
 		From(models.SubscriptionTable)
 
-	// Фильтрация по пользователю
-	if params.UserID != nil {
-		query = query.Where(squirrel.Eq{"user_id": *params.UserID})
+	query = applyListFilters(query, tx.tenant, params)
+
+	if params.CursorPaging && params.After != nil {
+		after, err := keysetPredicate(params.Sort, *params.After)
+		if err != nil {
+			return nil, fmt.Errorf("SubscriptionPostgres Get() %w", err)
+		}
+		query = query.Where(after)
+	}
+
+	// Сортировка; id в конце делает порядок детерминированным для пагинации
+	for _, sort := range params.Sort {
+		column, ok := sortColumns[sort.Field]
+		if !ok {
+			return nil, fmt.Errorf("SubscriptionPostgres Get() неизвестный ключ сортировки %q", sort.Field)
+		}
+		if sort.Desc {
+			column += " DESC"
+		}
+		query = query.OrderBy(column)
 	}
+	query = query.OrderBy("id")
 
 	// Пагинация
 	if params.Limit > 0 {
 		query = query.Limit(uint64(params.Limit))
 	}
-	if params.Page > 0 && params.Limit > 0 {
+	if !params.CursorPaging && params.Page > 0 && params.Limit > 0 {
 		offset := (params.Page - 1) * params.Limit
 		query = query.Offset(uint64(offset))
 	}
@@ -94,23 +164,15 @@ func (r *SubscriptionPostgres) Get(params models.SubscriptionParams) ([]models.S
 		return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка построения SQL-запроса: %w", err)
 	}
 
-	rows, err := r.db.Query(sqlQuery, args...)
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
-		return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка выполнения запроса: %w", err)
+		return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка выполнения запроса: %w", mapDBError(err))
 	}
 	defer rows.Close() //nolint:errcheck
 
 	var subscriptions []models.Subscription
 	for rows.Next() {
-		var sub models.Subscription
-		err := rows.Scan(
-			&sub.ID,
-			&sub.ServiceName,
-			&sub.Price,
-			&sub.UserID,
-			&sub.StartDate,
-			&sub.EndDate,
-		)
+		sub, err := scanSubscription(rows)
 		if err != nil {
 			return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка сканирования строки: %w", err)
 		}
@@ -118,25 +180,277 @@ func (r *SubscriptionPostgres) Get(params models.SubscriptionParams) ([]models.S
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка итерации по строкам: %w", err)
+		return nil, fmt.Errorf("SubscriptionPostgres Get() ошибка итерации по строкам: %w", mapDBError(err))
+	}
+	if err := tx.commit(); err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres Get() %w", err)
 	}
 
 	return subscriptions, nil
 }
 
-func (r *SubscriptionPostgres) Delete(id uuid.UUID) error {
+func (r *SubscriptionPostgres) GetByID(ctx context.Context, id uuid.UUID) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres GetByID() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres GetByID() %w", err)
+	}
+
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres GetByID() %w", err)
+	}
+	return sub, nil
+}
+
+// getByID читает подписку арендатора транзакции tx вместе с историей цен,
+// промо-периодами, приостановками и действующей отменой
+func getByID(ctx context.Context, tx *tenantTx, id uuid.UUID) (models.Subscription, error) {
+	query := squirrel.Select(subscriptionColumns...).
+		From(models.SubscriptionTable).
+		Where(tx.subscriptionKey(id)).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("ошибка построения SQL-запроса: %w", err)
+	}
+
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, sqlQuery, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, fmt.Errorf("запись с ID %s не найдена: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("ошибка выполнения запроса: %w", mapDBError(err))
+	}
+
+	if sub.PriceHistory, err = priceHistory(ctx, tx, id); err != nil {
+		return models.Subscription{}, err
+	}
+	if sub.Promotions, err = promotions(ctx, tx, id); err != nil {
+		return models.Subscription{}, err
+	}
+	if sub.Pauses, err = pauses(ctx, tx, id); err != nil {
+		return models.Subscription{}, err
+	}
+	if sub.Cancellation, err = activeCancellation(ctx, tx, id); err != nil {
+		return models.Subscription{}, err
+	}
+	return sub, nil
+}
+
+// subscriptionColumns — столбцы, читаемые scanSubscription, в порядке сканирования
+var subscriptionColumns = []string{
+	"id", "service_name", currentPriceSQL + " AS price", "user_id", "start_date", "end_date",
+	"billing_interval", "billing_interval_count", "billing_anchor_day", "trial_end", inTrialSQL + " AS in_trial",
+	stateSQL + " AS state", "version", "updated_at",
+}
+
+// scanSubscription читает подписку из строки, выбранной по subscriptionColumns
+func scanSubscription(row interface{ Scan(dest ...any) error }) (models.Subscription, error) {
+	var sub models.Subscription
+	err := row.Scan(
+		&sub.ID,
+		&sub.ServiceName,
+		&sub.Price,
+		&sub.UserID,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.BillingInterval,
+		&sub.BillingIntervalCount,
+		&sub.BillingAnchorDay,
+		&sub.TrialEnd,
+		&sub.InTrial,
+		&sub.State,
+		&sub.Version,
+		&sub.UpdatedAt,
+	)
+	sub.MonthlyPrice = sub.MonthlyEquivalent()
+	return sub, err
+}
+
+func (r *SubscriptionPostgres) Count(ctx context.Context, params models.SubscriptionParams) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres Count() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := applyListFilters(squirrel.Select("COUNT(*)").From(models.SubscriptionTable), tx.tenant, params).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres Count() ошибка построения SQL-запроса: %w", err)
+	}
+
+	var total int64
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres Count() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	if err := tx.commit(); err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres Count() %w", err)
+	}
+
+	return total, nil
+}
+
+// sortColumns сопоставляет ключи сортировки с выражениями SQL.
+// Бессрочные подписки сортируются как подписки с бесконечной датой окончания,
+// так что порядок совпадает с NULLS LAST и сравним в условии курсора.
+var sortColumns = map[string]string{
+	models.SortServiceName: "service_name",
+	models.SortPrice:       currentPriceSQL,
+	models.SortUserID:      "user_id",
+	models.SortStartDate:   "start_date",
+	models.SortEndDate:     "COALESCE(end_date, 'infinity'::date)",
+}
+
+// keysetPredicate строит условие «строка идёт после курсора» для сортировки sort
+// с id в качестве последнего ключа:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND id > cursor.ID).
+// Для ключей с обратным порядком сравнение меняется на «меньше».
+func keysetPredicate(sort []models.SortField, cursor models.Cursor) (squirrel.Sqlizer, error) {
+	if len(cursor.Values) != len(sort) {
+		return nil, fmt.Errorf("курсор не соответствует сортировке")
+	}
+
+	var (
+		or     squirrel.Or
+		prefix squirrel.And
+	)
+	for i, field := range sort {
+		column, ok := sortColumns[field.Field]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный ключ сортировки %q", field.Field)
+		}
+		op := " > ?"
+		if field.Desc {
+			op = " < ?"
+		}
+
+		term := append(squirrel.And{}, prefix...)
+		or = append(or, append(term, squirrel.Expr(column+op, cursor.Values[i])))
+		prefix = append(prefix, squirrel.Expr(column+" = ?", cursor.Values[i]))
+	}
+	or = append(or, append(prefix, squirrel.Expr("id > ?", cursor.ID)))
+
+	return or, nil
+}
+
+// applyListFilters ограничивает запрос арендатором tenant и добавляет фильтры списка подписок
+func applyListFilters(query squirrel.SelectBuilder, tenant string, params models.SubscriptionParams) squirrel.SelectBuilder {
+	query = query.Where(squirrel.Eq{"tenant_id": tenant})
+
+	// Фильтрация по пользователю
+	if params.UserID != nil {
+		query = query.Where(squirrel.Eq{"user_id": *params.UserID})
+	}
+
+	if params.ServiceName != "" {
+		query = query.Where(squirrel.Eq{"service_name": params.ServiceName})
+	}
+	if params.ServiceNamePrefix != "" {
+		query = query.Where("service_name LIKE ?", escapeLike(params.ServiceNamePrefix)+"%")
+	}
+
+	// Фильтры по цене сравнивают цену, действующую сейчас
+	if params.PriceMin != nil {
+		query = query.Where(currentPriceSQL+" >= ?", *params.PriceMin)
+	}
+	if params.PriceMax != nil {
+		query = query.Where(currentPriceSQL+" <= ?", *params.PriceMax)
+	}
+
+	if params.ActiveOn != nil {
+		query = query.Where(squirrel.LtOrEq{"start_date": *params.ActiveOn}).
+			Where("(end_date IS NULL OR end_date >= ?)", *params.ActiveOn)
+	}
+
+	if params.StartDateFrom != nil {
+		query = query.Where(squirrel.GtOrEq{"start_date": *params.StartDateFrom})
+	}
+	if params.StartDateTo != nil {
+		query = query.Where(squirrel.LtOrEq{"start_date": *params.StartDateTo})
+	}
+	if params.EndDateFrom != nil {
+		query = query.Where(squirrel.GtOrEq{"end_date": *params.EndDateFrom})
+	}
+	if params.EndDateTo != nil {
+		query = query.Where(squirrel.LtOrEq{"end_date": *params.EndDateTo})
+	}
+
+	if params.HasEndDate != nil {
+		if *params.HasEndDate {
+			query = query.Where("end_date IS NOT NULL")
+		} else {
+			query = query.Where("end_date IS NULL")
+		}
+	}
+
+	if params.InTrial != nil {
+		if *params.InTrial {
+			query = query.Where(inTrialSQL)
+		} else {
+			query = query.Where("NOT " + inTrialSQL)
+		}
+	}
+	if params.State != "" {
+		query = query.Where(stateSQL+" = ?", params.State)
+	}
+
+	// Первое платное списание — в месяце после окончания пробного периода
+	if params.TrialEndingWithinDays != nil {
+		query = query.Where("(trial_end + interval '1 month')::date - CURRENT_DATE BETWEEN 1 AND ?", *params.TrialEndingWithinDays)
+	}
+
+	return query
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Delete удаляет подписку. Если ifVersions не пуст, запись удаляется только
+// при совпадении её текущей версии с одной из перечисленных.
+func (r *SubscriptionPostgres) Delete(ctx context.Context, id uuid.UUID, ifVersions []int64) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return fmt.Errorf("SubscriptionPostgres Delete() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	query := squirrel.Delete(models.SubscriptionTable).
-		Where(squirrel.Eq{"id": id}).
+		Where(tx.subscriptionKey(id)).
 		PlaceholderFormat(squirrel.Dollar)
 
+	if len(ifVersions) > 0 {
+		query = query.Where(squirrel.Eq{"version": ifVersions})
+	}
+
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
 		return fmt.Errorf("SubscriptionPostgres Delete() ошибка построения SQL-запроса: %w", err)
 	}
 
-	result, err := r.db.Exec(sqlQuery, args...)
+	result, err := tx.ExecContext(ctx, sqlQuery, args...)
 	if err != nil {
-		return fmt.Errorf("SubscriptionPostgres Delete() ошибка выполнения запроса: %w", err)
+		return fmt.Errorf("SubscriptionPostgres Delete() ошибка выполнения запроса: %w", mapDBError(err))
 	}
 
 	// Проверяем, была ли удалена запись
@@ -146,19 +460,43 @@ func (r *SubscriptionPostgres) Delete(id uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("SubscriptionPostgres Delete() запись с ID %s не найдена", id)
+		return notAffectedError(ctx, tx, "Delete", id, ifVersions)
 	}
 
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("SubscriptionPostgres Delete() %w", err)
+	}
 	return nil
 }
 
-func (r *SubscriptionPostgres) Update(id uuid.UUID, subscription models.Subscription) error {
+// Update заменяет данные подписки и увеличивает её версию. Если ifVersions не пуст,
+// запись изменяется только при совпадении её текущей версии с одной из перечисленных.
+func (r *SubscriptionPostgres) Update(ctx context.Context, id uuid.UUID, subscription models.Subscription, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := lockEndDate(ctx, tx, id, subscription.EndDate); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+	}
+
 	builder := squirrel.Update(models.SubscriptionTable).
 		Set("service_name", subscription.ServiceName).
-		Set("price", subscription.Price).
 		Set("user_id", subscription.UserID).
 		Set("start_date", subscription.StartDate).
-		Where(squirrel.Eq{"id": id}).
+		Set("billing_interval", subscription.BillingInterval).
+		Set("billing_interval_count", subscription.BillingIntervalCount).
+		Set("billing_anchor_day", subscription.BillingAnchorDay).
+		Set("trial_end", subscription.TrialEnd).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where(tx.subscriptionKey(id)).
+		Suffix("RETURNING " + strings.Join(subscriptionColumns, ", ")).
 		PlaceholderFormat(squirrel.Dollar)
 
 	// Добавляем end_date, только если он есть
@@ -168,57 +506,442 @@ func (r *SubscriptionPostgres) Update(id uuid.UUID, subscription models.Subscrip
 		builder = builder.Set("end_date", nil)
 	}
 
+	if len(ifVersions) > 0 {
+		builder = builder.Where(squirrel.Eq{"version": ifVersions})
+	}
+
 	sqlQuery, args, err := builder.ToSql()
 	if err != nil {
-		return fmt.Errorf("SubscriptionPostgres Update() ошибка построения SQL-запроса: %w", err)
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() ошибка построения SQL-запроса: %w", err)
 	}
 
-	result, err := r.db.Exec(sqlQuery, args...)
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, sqlQuery, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, notAffectedError(ctx, tx, "Update", id, ifVersions)
+	}
 	if err != nil {
-		return fmt.Errorf("SubscriptionPostgres Update() ошибка выполнения запроса: %w", err)
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() ошибка выполнения запроса: %w", mapDBError(err))
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	// Цена не перезаписывается: новая цена действует с текущего месяца,
+	// а стоимость прошлых месяцев по-прежнему считается по старой
+	if sub.Price != subscription.Price {
+		if err := upsertPrice(ctx, tx, id, nil, subscription.Price); err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+		}
+	}
+	if err := replacePromotions(ctx, tx, id, subscription.Promotions); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+	}
+	if sub, err = getByID(ctx, tx, id); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+	}
+
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Update() %w", err)
+	}
+	return sub, nil
+}
+
+// Patch обновляет только столбцы, затронутые патчем, увеличивает версию
+// и возвращает подписку после изменения. ifVersions работает так же, как в Update.
+func (r *SubscriptionPostgres) Patch(ctx context.Context, id uuid.UUID, patch models.SubscriptionPatch, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, false)
 	if err != nil {
-		return fmt.Errorf("SubscriptionPostgres Update() ошибка получения количества изменённых строк: %w", err)
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
 	}
+	defer tx.Rollback() //nolint:errcheck
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("SubscriptionPostgres Update() запись с ID %s не найдена", id)
+	if patch.IsEmpty() {
+		sub, err := getByID(ctx, tx, id)
+		if err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+		}
+		if len(ifVersions) > 0 && !slices.Contains(ifVersions, sub.Version) {
+			return models.Subscription{}, notAffectedError(ctx, tx, "Patch", id, ifVersions)
+		}
+		return sub, nil
 	}
 
-	return nil
+	if patch.ClearEndDate || patch.EndDate != nil {
+		if err := lockEndDate(ctx, tx, id, patch.EndDate); err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+		}
+	}
+
+	builder := squirrel.Update(models.SubscriptionTable).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where(tx.subscriptionKey(id)).
+		Suffix("RETURNING " + strings.Join(subscriptionColumns, ", ")).
+		PlaceholderFormat(squirrel.Dollar)
+
+	if patch.ServiceName != nil {
+		builder = builder.Set("service_name", *patch.ServiceName)
+	}
+	if patch.UserID != nil {
+		builder = builder.Set("user_id", *patch.UserID)
+	}
+	if patch.StartDate != nil {
+		builder = builder.Set("start_date", *patch.StartDate)
+	}
+	if patch.ClearEndDate {
+		builder = builder.Set("end_date", nil)
+	} else if patch.EndDate != nil {
+		builder = builder.Set("end_date", *patch.EndDate)
+	}
+	if patch.BillingInterval != nil {
+		builder = builder.Set("billing_interval", *patch.BillingInterval)
+	}
+	if patch.BillingIntervalCount != nil {
+		builder = builder.Set("billing_interval_count", *patch.BillingIntervalCount)
+	}
+	if patch.BillingAnchorDay != nil {
+		builder = builder.Set("billing_anchor_day", *patch.BillingAnchorDay)
+	}
+	if patch.ClearTrialEnd {
+		builder = builder.Set("trial_end", nil)
+	} else if patch.TrialEnd != nil {
+		builder = builder.Set("trial_end", *patch.TrialEnd)
+	}
+
+	if len(ifVersions) > 0 {
+		builder = builder.Where(squirrel.Eq{"version": ifVersions})
+	}
+
+	sqlQuery, args, err := builder.ToSql()
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() ошибка построения SQL-запроса: %w", err)
+	}
+
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, sqlQuery, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, notAffectedError(ctx, tx, "Patch", id, ifVersions)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+
+	// Цена не перезаписывается: новая цена действует с текущего месяца,
+	// а стоимость прошлых месяцев по-прежнему считается по старой
+	if patch.Price != nil && *patch.Price != sub.Price {
+		if err := upsertPrice(ctx, tx, id, nil, *patch.Price); err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+		}
+	}
+	if patch.Promotions != nil {
+		if err := replacePromotions(ctx, tx, id, *patch.Promotions); err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+		}
+	}
+	if sub, err = getByID(ctx, tx, id); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+	}
+
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres Patch() %w", err)
+	}
+	return sub, nil
 }
 
-func (r *SubscriptionPostgres) GetCost(params models.SubscriptionParams) (int64, error) {
-	query := squirrel.Select("COALESCE(SUM(price), 0)").
-		From(models.SubscriptionTable).PlaceholderFormat(squirrel.Dollar)
+// SetPrice записывает цену, действующую с месяца change.EffectiveFrom: для прошлых
+// месяцев это исправление истории, для будущих — запланированное изменение.
+// Версия подписки увеличивается; ifVersions работает так же, как в Update.
+func (r *SubscriptionPostgres) SetPrice(ctx context.Context, id uuid.UUID, change models.PriceChange, ifVersions []int64) (models.Subscription, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
 
-	if !params.StartDate.IsZero() {
-		query = query.Where(squirrel.GtOrEq{"start_date": params.StartDate})
+	tx, err := beginTenant(ctx, r.db, false)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() %w", err)
 	}
-	if !params.EndDate.IsZero() {
-		query = query.Where(squirrel.LtOrEq{"end_date": params.EndDate})
+	defer tx.Rollback() //nolint:errcheck
+
+	builder := squirrel.Update(models.SubscriptionTable).
+		Set("version", squirrel.Expr("version + 1")).
+		Set("updated_at", squirrel.Expr("now()")).
+		Where(tx.subscriptionKey(id)).
+		Suffix("RETURNING start_date").
+		PlaceholderFormat(squirrel.Dollar)
+
+	if len(ifVersions) > 0 {
+		builder = builder.Where(squirrel.Eq{"version": ifVersions})
 	}
 
-	if params.UserID != nil {
-		query = query.Where(squirrel.Eq{"user_id": *params.UserID})
+	sqlQuery, args, err := builder.ToSql()
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() ошибка построения SQL-запроса: %w", err)
 	}
 
-	if params.ServiceName != "" {
-		query = query.Where(squirrel.Eq{"service_name": params.ServiceName})
+	var startDate time.Time
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&startDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Subscription{}, notAffectedError(ctx, tx, "SetPrice", id, ifVersions)
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	if change.EffectiveFrom.Before(startDate) {
+		return models.Subscription{}, models.NewValidationError("effective_from", "effective_from must not be before start_date")
+	}
+
+	if err := upsertPrice(ctx, tx, id, &change.EffectiveFrom, change.Price); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() %w", err)
+	}
+	sub, err := getByID(ctx, tx, id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() %w", err)
 	}
 
+	if err := tx.commit(); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionPostgres SetPrice() %w", err)
+	}
+	return sub, nil
+}
+
+// notAffectedError выясняет, почему условное изменение не затронуло ни одной строки:
+// записи нет (ErrNotFound) или её версия не совпала с ожидаемой (ErrPreconditionFailed).
+func notAffectedError(ctx context.Context, tx *tenantTx, method string, id uuid.UUID, ifVersions []int64) error {
+	if len(ifVersions) == 0 {
+		return fmt.Errorf("SubscriptionPostgres %s() запись с ID %s не найдена: %w", method, id, models.ErrNotFound)
+	}
+
+	sqlQuery, args, err := squirrel.Select("1").
+		From(models.SubscriptionTable).
+		Where(tx.subscriptionKey(id)).
+		Prefix("SELECT EXISTS(").
+		Suffix(")").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("SubscriptionPostgres %s() ошибка построения SQL-запроса: %w", method, err)
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("SubscriptionPostgres %s() ошибка проверки существования записи: %w", method, mapDBError(err))
+	}
+	if !exists {
+		return fmt.Errorf("SubscriptionPostgres %s() запись с ID %s не найдена: %w", method, id, models.ErrNotFound)
+	}
+	return fmt.Errorf("SubscriptionPostgres %s() версия записи с ID %s не совпадает с ожидаемой: %w", method, id, models.ErrPreconditionFailed)
+}
+
+func (r *SubscriptionPostgres) GetCost(ctx context.Context, params models.SubscriptionParams) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres GetCost() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query := squirrel.Select("COALESCE(SUM(b.amount), 0)::bigint").
+		FromSelect(billingCharges(tx.tenant, params), "b").
+		PlaceholderFormat(squirrel.Dollar)
+
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
 		return 0, fmt.Errorf("SubscriptionPostgres GetCost() ошибка построения SQL-запроса: %w", err)
 	}
 
 	var cost int64
-	err = r.db.QueryRow(sqlQuery, args...).Scan(&cost)
+	err = tx.QueryRowContext(ctx, sqlQuery, args...).Scan(&cost)
 	if err != nil {
-		return 0, fmt.Errorf("SubscriptionPostgres GetCost() ошибка выполнения запроса: %w", err)
+		return 0, fmt.Errorf("SubscriptionPostgres GetCost() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	if err := tx.commit(); err != nil {
+		return 0, fmt.Errorf("SubscriptionPostgres GetCost() %w", err)
 	}
 
 	return cost, nil
 }
+
+func (r *SubscriptionPostgres) GetCostBreakdown(ctx context.Context, params models.SubscriptionParams) ([]models.CostBucket, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenant(ctx, r.db, true)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	groupCols := []string{"b.month"}
+	for _, group := range params.GroupBy {
+		switch group {
+		case models.CostGroupServiceName, models.CostGroupUserID:
+			groupCols = append(groupCols, "b."+group)
+		default:
+			return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() неизвестное поле группировки %q", group)
+		}
+	}
+
+	query := squirrel.Select(groupCols...).
+		Columns("COALESCE(SUM(b.amount), 0)::bigint", "COUNT(DISTINCT b.id)").
+		FromSelect(billingCharges(tx.tenant, params), "b").
+		GroupBy(groupCols...).
+		OrderBy(groupCols...).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() ошибка построения SQL-запроса: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var buckets []models.CostBucket
+	for rows.Next() {
+		var bucket models.CostBucket
+		// Порядок приёмников совпадает с порядком столбцов в SELECT
+		dest := []any{&bucket.Month}
+		for _, group := range params.GroupBy {
+			switch group {
+			case models.CostGroupServiceName:
+				dest = append(dest, &bucket.ServiceName)
+			case models.CostGroupUserID:
+				bucket.UserID = new(uuid.UUID)
+				dest = append(dest, bucket.UserID)
+			}
+		}
+		dest = append(dest, &bucket.Total, &bucket.Subscriptions)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() ошибка сканирования строки: %w", err)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() ошибка итерации по строкам: %w", mapDBError(err))
+	}
+	if err := tx.commit(); err != nil {
+		return nil, fmt.Errorf("SubscriptionPostgres GetCostBreakdown() %w", err)
+	}
+
+	return buckets, nil
+}
+
+// Stats возвращает число подписок, активных в месяце month, и их суммарную цену
+// в пересчёте на месяц по сервисам; приостановленные в этом месяце подписки
+// не учитываются. Запрос читает данные всех арендаторов и складывает их, чтобы
+// метрики не раскрывали данные отдельного арендатора.
+func (r *SubscriptionPostgres) Stats(ctx context.Context, month time.Time) (models.SubscriptionStats, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tx, err := beginTenantTx(ctx, r.db, allTenants, true)
+	if err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Расходы считаются по цене в пересчёте на месяц: годовая подписка
+	// учитывается каждый месяц, а не только в месяц списания
+	query := squirrel.Select("s.service_name", "COUNT(*)").
+		// Подписки в пробном периоде активны, но расходов не дают
+		Column("COALESCE(ROUND(SUM(CASE WHEN s.trial_end >= ?::date THEN 0 ELSE "+
+			monthlyPriceSQL(chargeAmountSQL("?::date"))+" END)), 0)::bigint", month, month, month, month).
+		From(models.SubscriptionTable+" AS s").
+		Where("s.start_date <= ?", month).
+		Where("(s.end_date IS NULL OR s.end_date >= ?)", month).
+		Where("NOT "+pausedAt("s", "?::date"), month, month).
+		GroupBy("s.service_name").
+		OrderBy("s.service_name").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() ошибка построения SQL-запроса: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() ошибка выполнения запроса: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var stats models.SubscriptionStats
+	for rows.Next() {
+		var service models.ServiceStats
+		if err := rows.Scan(&service.ServiceName, &service.Active, &service.MonthlySpend); err != nil {
+			return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() ошибка сканирования строки: %w", err)
+		}
+		stats.Services = append(stats.Services, service)
+	}
+
+	if err = rows.Err(); err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() ошибка итерации по строкам: %w", mapDBError(err))
+	}
+
+	if err := tx.commit(); err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionPostgres Stats() %w", err)
+	}
+	return stats, nil
+}
+
+// billingCharges строит подзапрос, возвращающий по одной строке на каждое списание
+// по подписке внутри периода params.StartDate..params.EndDate (оба месяца включительно).
+// Даты списаний рассчитывает функция subscription_charge_dates по интервалу оплаты
+// подписки; после пробного периода расписание списаний начинается заново.
+// Сумма списания — цена промо-периода или цена, действующая на дату списания.
+// Списание уменьшается пропорционально приостановленным месяцам оплачиваемого им
+// периода (годовое списание при одном месяце приостановки — на 1/12) и пропускается,
+// если приостановлен весь период.
+// Подписка без end_date считается активной до конца периода.
+// Пустой tenant не ограничивает арендатора — для запросов по всем арендаторам.
+// Столбцы: id, tenant_id, service_name, user_id, charge_date, month, amount.
+func billingCharges(tenant string, params models.SubscriptionParams) squirrel.SelectBuilder {
+	query := squirrel.Select(
+		"s.id", "s.tenant_id", "s.service_name", "s.user_id", "c.charge_date",
+		"date_trunc('month', c.charge_date)::date AS month", "ROUND("+chargeAmountSQL("c.charge_date")+" * ps.share) AS amount").
+		From(models.SubscriptionTable+" AS s").
+		JoinClause(
+			"CROSS JOIN LATERAL subscription_charge_dates("+
+				"COALESCE((s.trial_end + interval '1 month')::date, s.start_date), s.end_date, "+
+				"s.billing_interval, s.billing_interval_count, s.billing_anchor_day, "+
+				"?::date, ?::date) AS c(charge_date)",
+			params.StartDate, params.EndDate).
+		JoinClause("CROSS JOIN LATERAL (SELECT "+unpausedShareSQL("c.charge_date")+" AS share) AS ps").
+		// Отсекаем подписки, не пересекающиеся с периодом, ещё до расчёта списаний
+		Where("s.start_date <= ?", params.EndDate).
+		Where("(s.end_date IS NULL OR s.end_date >= ?)", params.StartDate).
+		// Списания за полностью приостановленный период не выполняются
+		Where("ps.share > 0")
+
+	if tenant != "" {
+		query = query.Where(squirrel.Eq{"s.tenant_id": tenant})
+	}
+
+	if params.UserID != nil {
+		query = query.Where(squirrel.Eq{"s.user_id": *params.UserID})
+	}
+
+	if params.ServiceName != "" {
+		query = query.Where(squirrel.Eq{"s.service_name": params.ServiceName})
+	}
+
+	return query
+}
+
+// monthlyPriceSQL возвращает выражение цены price подписки s в пересчёте на месяц
+// без округления, по тем же коэффициентам, что и models.Subscription.MonthlyEquivalent
+func monthlyPriceSQL(price string) string {
+	var b strings.Builder
+	b.WriteString(price + "::numeric * CASE s.billing_interval")
+	for _, unit := range slices.Sorted(maps.Keys(models.BillingUnitsPerMonth)) {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %s", unit, strconv.FormatFloat(models.BillingUnitsPerMonth[unit], 'f', -1, 64))
+	}
+	b.WriteString(" END / s.billing_interval_count")
+	return b.String()
+}
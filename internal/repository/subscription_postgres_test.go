@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestKeysetPredicate(t *testing.T) {
+	id := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+
+	tests := []struct {
+		name     string
+		sort     []models.SortField
+		values   []string
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "id only",
+			wantSQL:  "((id > ?))",
+			wantArgs: []any{id},
+		},
+		{
+			name:     "ascending key",
+			sort:     []models.SortField{{Field: models.SortServiceName}},
+			values:   []string{"Netflix"},
+			wantSQL:  "((service_name > ?) OR (service_name = ? AND id > ?))",
+			wantArgs: []any{"Netflix", "Netflix", id},
+		},
+		{
+			name:   "descending key and end date",
+			sort:   []models.SortField{{Field: models.SortStartDate, Desc: true}, {Field: models.SortEndDate}},
+			values: []string{"2026-04-01", "infinity"},
+			wantSQL: "((start_date < ?) OR (start_date = ? AND COALESCE(end_date, 'infinity'::date) > ?) OR " +
+				"(start_date = ? AND COALESCE(end_date, 'infinity'::date) = ? AND id > ?))",
+			wantArgs: []any{"2026-04-01", "2026-04-01", "infinity", "2026-04-01", "infinity", id},
+		},
+		{
+			name:    "values do not match sort",
+			sort:    []models.SortField{{Field: models.SortPrice}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			sort:    []models.SortField{{Field: "created_at"}},
+			values:  []string{"2026-04-01"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicate, err := keysetPredicate(tt.sort, models.Cursor{Sort: tt.sort, Values: tt.values, ID: id})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("keysetPredicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			sql, args, err := predicate.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("keysetPredicate() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("keysetPredicate() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBillingCharges(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+
+	tests := []struct {
+		name      string
+		tenant    string
+		params    models.SubscriptionParams
+		wantWhere []string
+		wantArgs  []any
+	}{
+		{
+			name:      "all tenants",
+			params:    models.SubscriptionParams{StartDate: start, EndDate: end},
+			wantWhere: []string{"s.start_date <= ?", "(s.end_date IS NULL OR s.end_date >= ?)", "ps.share > 0"},
+			wantArgs:  []any{start, end, end, start},
+		},
+		{
+			name:      "tenant, user and service",
+			tenant:    "acme",
+			params:    models.SubscriptionParams{StartDate: start, EndDate: end, UserID: &userID, ServiceName: "Netflix"},
+			wantWhere: []string{"s.tenant_id = ?", "s.user_id = ?", "s.service_name = ?"},
+			// squirrel.Eq передаёт uuid.UUID как driver.Valuer, то есть строкой
+			wantArgs: []any{start, end, end, start, "acme", userID.String(), "Netflix"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := billingCharges(tt.tenant, tt.params).ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			// Период передаётся в subscription_charge_dates, затем отсекает подписки вне его
+			if !strings.Contains(sql, "subscription_charge_dates(") {
+				t.Errorf("billingCharges() SQL = %q, want charge dates from subscription_charge_dates", sql)
+			}
+			for _, where := range tt.wantWhere {
+				if !strings.Contains(sql, where) {
+					t.Errorf("billingCharges() SQL = %q, want condition %q", sql, where)
+				}
+			}
+			if tt.tenant == "" && strings.Contains(sql, "s.tenant_id = ?") {
+				t.Errorf("billingCharges() SQL = %q, want no tenant condition", sql)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("billingCharges() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+const subscriptionPriceTable = "subscription_price"
+
+// priceAt возвращает выражение цены подписки из таблицы table, действующей на дату at:
+// цену последнего изменения не позже at, а до первого изменения — subscription.price
+func priceAt(table, at string) string {
+	return "COALESCE((SELECT p.price FROM " + subscriptionPriceTable + " p WHERE p.subscription_id = " + table + ".id" +
+		" AND p.effective_from <= " + at + " ORDER BY p.effective_from DESC LIMIT 1), " + table + ".price)"
+}
+
+// currentPriceSQL — цена подписки, действующая сегодня. Подписка, которая ещё
+// не началась, показывается с ценой на момент начала: изменение цены такой
+// подписки записывается с её start_date.
+var currentPriceSQL = priceAt(models.SubscriptionTable,
+	"GREATEST(CURRENT_DATE, "+models.SubscriptionTable+".start_date)")
+
+// upsertPrice записывает цену price подписки id, действующую с месяца from.
+// Без from цена меняется с текущего месяца, но не раньше начала подписки.
+func upsertPrice(ctx context.Context, tx *tenantTx, id uuid.UUID, from *time.Time, price int64) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO "+subscriptionPriceTable+" (subscription_id, effective_from, price) "+
+			"SELECT id, COALESCE($2::date, GREATEST(date_trunc('month', CURRENT_DATE)::date, start_date)), $3 "+
+			"FROM "+models.SubscriptionTable+" WHERE id = $1 AND tenant_id = $4 "+
+			"ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price, created_at = now()",
+		id, from, price, tx.tenant)
+	if err != nil {
+		return fmt.Errorf("ошибка записи цены: %w", mapDBError(err))
+	}
+	return nil
+}
+
+// priceHistory возвращает цены подписки id по возрастанию месяца, начиная с цены
+// на момент начала подписки
+func priceHistory(ctx context.Context, tx *tenantTx, id uuid.UUID) ([]models.PriceChange, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT s.start_date, s.price FROM "+models.SubscriptionTable+" s WHERE s.id = $1 AND s.tenant_id = $2 "+
+			"AND NOT EXISTS (SELECT 1 FROM "+subscriptionPriceTable+" p WHERE p.subscription_id = s.id AND p.effective_from <= s.start_date) "+
+			"UNION ALL "+
+			"SELECT effective_from, price FROM "+subscriptionPriceTable+" WHERE subscription_id = $1 "+
+			"ORDER BY 1",
+		id, tx.tenant)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения истории цен: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var history []models.PriceChange
+	for rows.Next() {
+		var change models.PriceChange
+		if err := rows.Scan(&change.EffectiveFrom, &change.Price); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования истории цен: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения истории цен: %w", mapDBError(err))
+	}
+	return history, nil
+}
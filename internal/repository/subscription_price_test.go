@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCurrentPriceSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		// Изменение цены подписки, которая ещё не началась, записывается с её start_date;
+		// сравнение только с CURRENT_DATE не находило такую запись
+		{name: "scheduled subscription is priced at its start", want: "p.effective_from <= GREATEST(CURRENT_DATE, subscription.start_date)"},
+		{name: "latest change wins", want: "ORDER BY p.effective_from DESC LIMIT 1"},
+		{name: "falls back to the initial price", want: "), subscription.price)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(currentPriceSQL, tt.want) {
+				t.Errorf("currentPriceSQL = %q, want it to contain %q", currentPriceSQL, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+const subscriptionPromotionTable = "subscription_promotion"
+
+// inTrialSQL — подписка началась и её пробный период идёт в текущем месяце
+const inTrialSQL = "(start_date <= CURRENT_DATE AND COALESCE(trial_end >= date_trunc('month', CURRENT_DATE)::date, false))"
+
+// chargeAmountSQL возвращает выражение суммы списания подписки s на дату at:
+// цена промо-периода, если дата в него попадает, иначе действующая цена
+func chargeAmountSQL(at string) string {
+	return "COALESCE((SELECT pr.price FROM " + subscriptionPromotionTable + " pr WHERE pr.subscription_id = s.id" +
+		" AND " + at + " >= pr.start_month AND " + at + " < pr.end_month + interval '1 month'" +
+		" ORDER BY pr.start_month DESC LIMIT 1), " + priceAt("s", at) + ")"
+}
+
+// replacePromotions заменяет промо-периоды подписки id на promotions
+func replacePromotions(ctx context.Context, tx *tenantTx, id uuid.UUID, promotions []models.Promotion) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM "+subscriptionPromotionTable+" WHERE subscription_id = $1", id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления промо-периодов: %w", mapDBError(err))
+	}
+	if len(promotions) == 0 {
+		return nil
+	}
+
+	builder := squirrel.Insert(subscriptionPromotionTable).
+		Columns("subscription_id", "start_month", "end_month", "price").
+		PlaceholderFormat(squirrel.Dollar)
+	for _, promotion := range promotions {
+		builder = builder.Values(id, promotion.StartMonth, promotion.EndMonth, promotion.Price)
+	}
+
+	sqlQuery, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("ошибка построения SQL-запроса: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return fmt.Errorf("ошибка записи промо-периодов: %w", mapDBError(err))
+	}
+	return nil
+}
+
+// promotions возвращает промо-периоды подписки id по возрастанию начала
+func promotions(ctx context.Context, tx *tenantTx, id uuid.UUID) ([]models.Promotion, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT start_month, end_month, price FROM "+subscriptionPromotionTable+
+			" WHERE subscription_id = $1 ORDER BY start_month", id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения промо-периодов: %w", mapDBError(err))
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var result []models.Promotion
+	for rows.Next() {
+		var promotion models.Promotion
+		if err := rows.Scan(&promotion.StartMonth, &promotion.EndMonth, &promotion.Price); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования промо-периода: %w", err)
+		}
+		result = append(result, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения промо-периодов: %w", mapDBError(err))
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+// allTenants — значение app.tenant_id, открывающее чтение всех арендаторов (см. миграцию с RLS)
+const allTenants = "*"
+
+// errTenantRequired возвращается, если запрос к данным арендатора выполняется без арендатора в контексте
+var errTenantRequired = errors.New("tenant is not set in context")
+
+// tenantTx — транзакция, в которой Postgres применяет политику RLS арендатора tenant.
+// Условие tenant_id в запросах остаётся основной защитой, RLS — дополнительной.
+// owner — пользователь, которым ограничен вызывающий (см. models.ContextWithOwner).
+type tenantTx struct {
+	tracedTx
+	tenant string
+	owner  *uuid.UUID
+}
+
+// beginTenant начинает транзакцию от имени арендатора из ctx
+func beginTenant(ctx context.Context, db tracedDB, readOnly bool) (*tenantTx, error) {
+	tenant, ok := models.TenantFromContext(ctx)
+	if !ok {
+		return nil, errTenantRequired
+	}
+	tx, err := beginTenantTx(ctx, db, tenant, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	if owner, ok := models.OwnerFromContext(ctx); ok {
+		tx.owner = &owner
+	}
+	return tx, nil
+}
+
+func beginTenantTx(ctx context.Context, db tracedDB, tenant string, readOnly bool) (*tenantTx, error) {
+	tx, err := db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", mapDBError(err))
+	}
+
+	t := &tenantTx{tracedTx: tracedTx{tx}, tenant: tenant}
+	// set_config(..., true) действует до конца транзакции, как SET LOCAL,
+	// но, в отличие от него, принимает значение параметром запроса
+	if _, err := t.ExecContext(ctx, "SELECT set_config('app.tenant_id', $1, true)", tenant); err != nil {
+		tx.Rollback() //nolint:errcheck
+		return nil, fmt.Errorf("ошибка установки арендатора: %w", mapDBError(err))
+	}
+	return t, nil
+}
+
+// subscriptionKey возвращает условие выбора подписки id, доступной в транзакции:
+// чужая подписка не находится, как и несуществующая
+func (t *tenantTx) subscriptionKey(id uuid.UUID) squirrel.Eq {
+	key := squirrel.Eq{"id": id, "tenant_id": t.tenant}
+	if t.owner != nil {
+		key["user_id"] = *t.owner
+	}
+	return key
+}
+
+func (t *tenantTx) commit() error {
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", mapDBError(err))
+	}
+	return nil
+}
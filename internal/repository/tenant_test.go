@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+func TestSubscriptionKey(t *testing.T) {
+	id := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	owner := uuid.MustParse("0b3bd2a4-6f47-4a53-9bd0-0f5c1a2e7d11")
+
+	tests := []struct {
+		name  string
+		owner *uuid.UUID
+		want  squirrel.Eq
+	}{
+		{name: "unrestricted", want: squirrel.Eq{"id": id, "tenant_id": "acme"}},
+		// Владелец входит в условие самой команды, а не проверяется отдельным запросом
+		{name: "restricted to owner", owner: &owner, want: squirrel.Eq{"id": id, "tenant_id": "acme", "user_id": owner}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &tenantTx{tenant: "acme", owner: tt.owner}
+			if got := tx.subscriptionKey(id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subscriptionKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
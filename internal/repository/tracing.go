@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/tracing"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/BountyM/effectiveMobileTestTask/internal/repository")
+
+// queryer — общие методы *sqlx.DB и *sqlx.Tx, через которые выполняются запросы
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// tracedDB создаёт спан на каждый запрос к базе. Текст запроса записывается
+// в атрибут db.query.text без литералов, значения параметров не записываются.
+type tracedDB struct {
+	*sqlx.DB
+}
+
+func (db tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tracedQuery(ctx, db.DB, query, args...)
+}
+
+func (db tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tracedQueryRow(ctx, db.DB, query, args...)
+}
+
+func (db tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tracedExec(ctx, db.DB, query, args...)
+}
+
+// tracedTx — транзакция, запросы которой трассируются так же, как в tracedDB
+type tracedTx struct {
+	*sqlx.Tx
+}
+
+func (tx tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tracedQuery(ctx, tx.Tx, query, args...)
+}
+
+func (tx tracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tracedQueryRow(ctx, tx.Tx, query, args...)
+}
+
+func (tx tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tracedExec(ctx, tx.Tx, query, args...)
+}
+
+func tracedQuery(ctx context.Context, q queryer, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func tracedQueryRow(ctx context.Context, q queryer, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := q.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func tracedExec(ctx context.Context, q queryer, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	res, err := q.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return res, err
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(sanitizeSQL(query)),
+		))
+}
+
+var (
+	sqlStringLiteralRe = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteralRe = regexp.MustCompile(`(^|[^$\w])\d+(?:\.\d+)?\b`)
+	sqlWhitespaceRe    = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL заменяет строковые и числовые литералы на ? и схлопывает пробелы.
+// Параметры вида $1 остаются как есть.
+func sanitizeSQL(query string) string {
+	query = sqlStringLiteralRe.ReplaceAllString(query, "?")
+	query = sqlNumberLiteralRe.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(sqlWhitespaceRe.ReplaceAllString(query, " "))
+}
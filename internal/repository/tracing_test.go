@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "placeholders are kept",
+			query: "SELECT id FROM subscription WHERE id = $1 AND tenant_id = $2",
+			want:  "SELECT id FROM subscription WHERE id = $1 AND tenant_id = $2",
+		},
+		{
+			name:  "string literal",
+			query: "SELECT set_config('app.tenant_id', $1, true)",
+			want:  "SELECT set_config(?, $1, true)",
+		},
+		{
+			name:  "escaped quote inside literal",
+			query: "SELECT 'it''s' AS note",
+			want:  "SELECT ? AS note",
+		},
+		{
+			name:  "integer and decimal literals",
+			query: "SELECT price * 12 / 1.5 FROM subscription LIMIT 10",
+			want:  "SELECT price * ? / ? FROM subscription LIMIT ?",
+		},
+		{
+			name:  "digits inside identifiers",
+			query: "SELECT s1.id FROM subscription s1",
+			want:  "SELECT s1.id FROM subscription s1",
+		},
+		{
+			name:  "whitespace is collapsed",
+			query: "\n\tSELECT id\n\t  FROM subscription  \n",
+			want:  "SELECT id FROM subscription",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeSQL(tt.query); got != tt.want {
+				t.Errorf("sanitizeSQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartQuerySpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	tests := []struct {
+		name          string
+		query         string
+		wantOperation string
+		wantText      string
+	}{
+		{
+			name:          "select",
+			query:         "SELECT id FROM subscription WHERE price > 100",
+			wantOperation: "SELECT",
+			wantText:      "SELECT id FROM subscription WHERE price > ?",
+		},
+		{
+			name:          "lower case with leading whitespace",
+			query:         "\n  update subscription set version = version + 1 where id = $1",
+			wantOperation: "UPDATE",
+			wantText:      "update subscription set version = version + ? where id = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, span := startQuerySpan(context.Background(), tt.query)
+			span.End()
+
+			spans := recorder.Ended()
+			got := spans[len(spans)-1]
+			if got.Name() != tt.wantOperation {
+				t.Errorf("span name = %q, want %q", got.Name(), tt.wantOperation)
+			}
+			if got.SpanKind() != trace.SpanKindClient {
+				t.Errorf("span kind = %v, want client", got.SpanKind())
+			}
+			attrs := make(map[string]string)
+			for _, attr := range got.Attributes() {
+				attrs[string(attr.Key)] = attr.Value.Emit()
+			}
+			if attrs[string(semconv.DBOperationNameKey)] != tt.wantOperation {
+				t.Errorf("%s = %q, want %q", semconv.DBOperationNameKey, attrs[string(semconv.DBOperationNameKey)], tt.wantOperation)
+			}
+			if attrs[string(semconv.DBQueryTextKey)] != tt.wantText {
+				t.Errorf("%s = %q, want %q", semconv.DBQueryTextKey, attrs[string(semconv.DBQueryTextKey)], tt.wantText)
+			}
+		})
+	}
+}
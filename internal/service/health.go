@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/BountyM/effectiveMobileTestTask/internal/repository"
+)
+
+type HealthService struct {
+	repository repository.Health
+	build      models.BuildInfo
+}
+
+func newHealthService(repository repository.Health, build models.BuildInfo) *HealthService {
+	return &HealthService{repository: repository, build: build}
+}
+
+type Health interface {
+	Ready(ctx context.Context) (models.Readiness, error)
+}
+
+// Ready проверяет доступность базы и возвращает версию схемы и сведения о сборке.
+// Сведения о сборке возвращаются и при ошибке, чтобы их можно было показать в ответе.
+func (s *HealthService) Ready(ctx context.Context) (models.Readiness, error) {
+	readiness := models.Readiness{Build: s.build}
+
+	if err := s.repository.Ping(ctx); err != nil {
+		return readiness, fmt.Errorf("HealthService Ready() %w", err)
+	}
+
+	version, err := s.repository.SchemaVersion(ctx)
+	if err != nil {
+		return readiness, fmt.Errorf("HealthService Ready() %w", err)
+	}
+	readiness.SchemaVersion = version
+	return readiness, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/BountyM/effectiveMobileTestTask/internal/repository"
+)
+
+type IdempotencyService struct {
+	repository repository.Idempotency
+}
+
+func newIdempotencyService(repository repository.Idempotency) *IdempotencyService {
+	return &IdempotencyService{repository: repository}
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record models.IdempotencyRecord) error
+	Release(ctx context.Context, record models.IdempotencyRecord) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// Begin занимает ключ для нового запроса. Если по ключу уже есть запись,
+// она возвращается: с тем же отпечатком запроса её ответ нужно повторить,
+// с другим — ключ использован повторно для иного запроса (ErrValidation).
+func (s *IdempotencyService) Begin(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	existing, err := s.repository.Reserve(ctx, record)
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyService Begin() %w", err)
+	}
+	if existing != nil && existing.Fingerprint != record.Fingerprint {
+		return nil, fmt.Errorf("IdempotencyService Begin() %w",
+			models.NewValidationError("Idempotency-Key", "Idempotency-Key was already used with a different request"))
+	}
+	return existing, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, record models.IdempotencyRecord) error {
+	if err := s.repository.Complete(ctx, record); err != nil {
+		return fmt.Errorf("IdempotencyService Complete() %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Release(ctx context.Context, record models.IdempotencyRecord) error {
+	if err := s.repository.Release(ctx, record); err != nil {
+		return fmt.Errorf("IdempotencyService Release() %w", err)
+	}
+	return nil
+}
+
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := s.repository.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("IdempotencyService PurgeExpired() %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+// accessScope описывает, к чьим подпискам есть доступ у вызывающего.
+// Пользователь видит и изменяет только свои подписки, администратор — все.
+// Контекст без принципала (аутентификация отключена, фоновые задачи) ограничений не имеет.
+type accessScope struct {
+	restricted bool
+	userID     uuid.UUID
+}
+
+// scopeFromContext определяет права вызывающего по принципалу из ctx.
+// Пользователем считается принципал, чей subject — UUID; остальным
+// принципалам без роли администратора доступ запрещён.
+func scopeFromContext(ctx context.Context) (accessScope, error) {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if !ok || principal.HasRole(models.RoleAdmin) {
+		return accessScope{}, nil
+	}
+
+	userID, err := uuid.Parse(principal.Subject)
+	if err != nil {
+		return accessScope{}, fmt.Errorf("%w: principal %q is neither a user nor an admin", models.ErrForbidden, principal.Subject)
+	}
+	return accessScope{restricted: true, userID: userID}, nil
+}
+
+// owns сообщает, доступна ли вызывающему подписка пользователя userID
+func (a accessScope) owns(userID uuid.UUID) bool {
+	return !a.restricted || a.userID == userID
+}
+
+// restrict ограничивает выборку подписками вызывающего. Запрос подписок
+// другого пользователя отклоняется с ErrNotFound, как и доступ к чужой записи.
+func (a accessScope) restrict(params *models.SubscriptionParams) error {
+	if !a.restricted {
+		return nil
+	}
+	if params.UserID != nil && *params.UserID != a.userID {
+		return fmt.Errorf("%w: subscriptions of user %s", models.ErrNotFound, *params.UserID)
+	}
+	params.UserID = &a.userID
+	return nil
+}
+
+// assign проверяет, что вызывающий может назначить подписку пользователю userID
+func (a accessScope) assign(userID uuid.UUID) error {
+	if !a.owns(userID) {
+		return fmt.Errorf("%w: subscription cannot be assigned to another user", models.ErrForbidden)
+	}
+	return nil
+}
+
+// bind ограничивает подписки, читаемые и изменяемые по ID в контексте ctx, подписками
+// вызывающего. Владелец входит в условие той же команды, что читает или изменяет
+// запись, поэтому между проверкой и изменением подписка не может стать чужой.
+// Чужая подписка неотличима от несуществующей.
+func (a accessScope) bind(ctx context.Context) context.Context {
+	if !a.restricted {
+		return ctx
+	}
+	return models.ContextWithOwner(ctx, a.userID)
+}
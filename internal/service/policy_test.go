@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestAccessScope(t *testing.T) {
+	user := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	other := uuid.MustParse("0b3bd2a4-6f47-4a53-9bd0-0f5c1a2e7d11")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// wantScopeErr — ошибка определения прав вызывающего
+		wantScopeErr error
+		// wantOwner — пользователь, которым ограничены подписки по ID; uuid.Nil — без ограничения
+		wantOwner uuid.UUID
+		// Запрос списка подписок пользователя other и назначение ему подписки
+		wantRestrictErr error
+		wantAssignErr   error
+	}{
+		{name: "no principal", ctx: context.Background()},
+		{
+			name: "admin is unrestricted",
+			ctx:  models.ContextWithPrincipal(context.Background(), models.Principal{Subject: user.String(), Roles: []string{models.RoleAdmin}}),
+		},
+		{
+			name:            "user is limited to own subscriptions",
+			ctx:             models.ContextWithPrincipal(context.Background(), models.Principal{Subject: user.String()}),
+			wantOwner:       user,
+			wantRestrictErr: models.ErrNotFound,
+			wantAssignErr:   models.ErrForbidden,
+		},
+		{
+			name:         "service without admin role",
+			ctx:          models.ContextWithPrincipal(context.Background(), models.Principal{Subject: "billing", Method: models.AuthMethodAPIKey}),
+			wantScopeErr: models.ErrForbidden,
+		},
+		{
+			name: "service with admin role",
+			ctx: models.ContextWithPrincipal(context.Background(),
+				models.Principal{Subject: "billing", Roles: []string{models.RoleAdmin}, Method: models.AuthMethodAPIKey}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := scopeFromContext(tt.ctx)
+			if !errors.Is(err, tt.wantScopeErr) {
+				t.Fatalf("scopeFromContext() error = %v, want %v", err, tt.wantScopeErr)
+			}
+			if err != nil {
+				return
+			}
+
+			owner, bound := models.OwnerFromContext(scope.bind(tt.ctx))
+			if bound != (tt.wantOwner != uuid.Nil) || owner != tt.wantOwner {
+				t.Errorf("bind() owner = %v, %v, want %v", owner, bound, tt.wantOwner)
+			}
+
+			params := models.SubscriptionParams{UserID: &other}
+			if err := scope.restrict(&params); !errors.Is(err, tt.wantRestrictErr) {
+				t.Errorf("restrict(other user) error = %v, want %v", err, tt.wantRestrictErr)
+			}
+			if err := scope.assign(other); !errors.Is(err, tt.wantAssignErr) {
+				t.Errorf("assign(other user) error = %v, want %v", err, tt.wantAssignErr)
+			}
+		})
+	}
+}
+
+func TestAccessScopeRestrict(t *testing.T) {
+	user := uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+
+	tests := []struct {
+		name   string
+		scope  accessScope
+		userID *uuid.UUID
+		want   *uuid.UUID
+	}{
+		{name: "unrestricted listing stays global", scope: accessScope{}},
+		{name: "user listing is limited to the user", scope: accessScope{restricted: true, userID: user}, want: &user},
+		{name: "user may ask for own subscriptions", scope: accessScope{restricted: true, userID: user}, userID: &user, want: &user},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := models.SubscriptionParams{UserID: tt.userID}
+			if err := tt.scope.restrict(&params); err != nil {
+				t.Fatalf("restrict() unexpected error: %v", err)
+			}
+			if (params.UserID == nil) != (tt.want == nil) || (params.UserID != nil && *params.UserID != *tt.want) {
+				t.Errorf("restrict() UserID = %v, want %v", params.UserID, tt.want)
+			}
+		})
+	}
+}
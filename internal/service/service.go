@@ -1,15 +1,21 @@
 package service
 
 import (
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
 	"github.com/BountyM/effectiveMobileTestTask/internal/repository"
 )
 
 type Service struct {
 	Subscription
+	Idempotency Idempotency
+	Health      Health
 }
 
-func New(repository *repository.Repository) *Service {
+func New(repository *repository.Repository, metrics *metrics.Metrics, build models.BuildInfo) *Service {
 	return &Service{
-		Subscription: newSubscriptionService(*repository),
+		Subscription: newSubscriptionService(*repository, metrics),
+		Idempotency:  newIdempotencyService(repository.Idempotency),
+		Health:       newHealthService(repository.Health, build),
 	}
 }
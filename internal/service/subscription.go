@@ -1,40 +1,83 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/BountyM/effectiveMobileTestTask/internal/metrics"
 	"github.com/BountyM/effectiveMobileTestTask/internal/models"
 	"github.com/BountyM/effectiveMobileTestTask/internal/repository"
+	"github.com/BountyM/effectiveMobileTestTask/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/BountyM/effectiveMobileTestTask/internal/service")
+
 type SubscriptionService struct {
 	repository repository.Repository
+	metrics    *metrics.Metrics
 }
 
-func newSubscriptionService(repository repository.Repository) *SubscriptionService {
-	return &SubscriptionService{repository: repository}
+func newSubscriptionService(repository repository.Repository, metrics *metrics.Metrics) *SubscriptionService {
+	return &SubscriptionService{repository: repository, metrics: metrics}
 }
 
 type Subscription interface {
-	Create(subscription models.Subscription) (uuid.UUID, error)
-	Get(params models.SubscriptionParams) ([]models.Subscription, error)
-	Delete(uuid.UUID) error
-	Update(uuid uuid.UUID, subscription models.Subscription) error
-	GetCost(params models.SubscriptionParams) (int64, error)
+	Create(ctx context.Context, subscription models.Subscription) (uuid.UUID, error)
+	Get(ctx context.Context, params models.SubscriptionParams) ([]models.Subscription, error)
+	GetByID(ctx context.Context, id uuid.UUID) (models.Subscription, error)
+	List(ctx context.Context, params models.SubscriptionParams) (models.SubscriptionList, error)
+	Delete(ctx context.Context, id uuid.UUID, ifVersions []int64) error
+	Update(ctx context.Context, id uuid.UUID, subscription models.Subscription, ifVersions []int64) (models.Subscription, error)
+	Patch(ctx context.Context, id uuid.UUID, patch models.SubscriptionPatch, ifVersions []int64) (models.Subscription, error)
+	SetPrice(ctx context.Context, id uuid.UUID, change models.PriceChange, ifVersions []int64) (models.Subscription, error)
+	Pause(ctx context.Context, id uuid.UUID, pause models.Pause, ifVersions []int64) (models.Subscription, error)
+	Resume(ctx context.Context, id uuid.UUID, month time.Time, ifVersions []int64) (models.Subscription, error)
+	CancelPause(ctx context.Context, id uuid.UUID, startMonth time.Time, ifVersions []int64) (models.Subscription, error)
+	Cancel(ctx context.Context, id uuid.UUID, req models.CancelRequest, ifVersions []int64) (models.Subscription, error)
+	Uncancel(ctx context.Context, id uuid.UUID, ifVersions []int64) (models.Subscription, error)
+	CancellationReasons(ctx context.Context, params models.SubscriptionParams) ([]models.CancellationReasonStats, error)
+	GetCost(ctx context.Context, params models.SubscriptionParams) (int64, error)
+	GetCostBreakdown(ctx context.Context, params models.SubscriptionParams) ([]models.CostBucket, error)
+	Stats(ctx context.Context) (models.SubscriptionStats, error)
 }
 
-func (s *SubscriptionService) Create(subscription models.Subscription) (uuid.UUID, error) {
-	res, err := s.repository.Create(subscription)
+func (s *SubscriptionService) Create(ctx context.Context, subscription models.Subscription) (_ uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("SubscriptionService Create() %w", err)
+	}
+	if err := scope.assign(subscription.UserID); err != nil {
+		return uuid.Nil, fmt.Errorf("SubscriptionService Create() %w", err)
+	}
+
+	res, err := s.repository.Create(ctx, subscription)
 	if err != nil {
 
 		return uuid.Nil, fmt.Errorf("SubscriptionService Create() %w", err)
 	}
+	s.metrics.SubscriptionsCreated.Inc()
 	return res, err
 }
 
-func (s *SubscriptionService) Get(params models.SubscriptionParams) ([]models.Subscription, error) {
-	res, err := s.repository.Get(params)
+func (s *SubscriptionService) Get(ctx context.Context, params models.SubscriptionParams) (_ []models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Get")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionService Get() %w", err)
+	}
+	if err := scope.restrict(&params); err != nil {
+		return nil, fmt.Errorf("SubscriptionService Get() %w", err)
+	}
+
+	res, err := s.repository.Get(ctx, params)
 	if err != nil {
 
 		return nil, fmt.Errorf("SubscriptionService Get() %w", err)
@@ -42,28 +85,346 @@ func (s *SubscriptionService) Get(params models.SubscriptionParams) ([]models.Su
 	return res, err
 }
 
-func (s *SubscriptionService) Delete(id uuid.UUID) error {
-	err := s.repository.Delete(id)
+func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetByID")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService GetByID() %w", err)
+	}
+
+	res, err := s.repository.GetByID(scope.bind(ctx), id)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService GetByID() %w", err)
+	}
+	return res, nil
+}
+
+// List возвращает страницу подписок. При чтении по смещению вместе со страницей
+// возвращается общее число записей, подходящих под фильтры, при чтении по курсору —
+// курсор следующей страницы.
+func (s *SubscriptionService) List(ctx context.Context, params models.SubscriptionParams) (_ models.SubscriptionList, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.List")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.SubscriptionList{}, fmt.Errorf("SubscriptionService List() %w", err)
+	}
+	if err := scope.restrict(&params); err != nil {
+		return models.SubscriptionList{}, fmt.Errorf("SubscriptionService List() %w", err)
+	}
+
+	if params.CursorPaging {
+		return s.listByCursor(ctx, params)
+	}
+
+	subscriptions, err := s.repository.Get(ctx, params)
+	if err != nil {
+		return models.SubscriptionList{}, fmt.Errorf("SubscriptionService List() %w", err)
+	}
+
+	total, err := s.repository.Count(ctx, params)
+	if err != nil {
+		return models.SubscriptionList{}, fmt.Errorf("SubscriptionService List() %w", err)
+	}
+
+	if subscriptions == nil {
+		subscriptions = []models.Subscription{}
+	}
+	return models.SubscriptionList{Subscriptions: subscriptions, Total: total}, nil
+}
+
+func (s *SubscriptionService) listByCursor(ctx context.Context, params models.SubscriptionParams) (models.SubscriptionList, error) {
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := params.Limit
+	params.Limit = limit + 1
+
+	subscriptions, err := s.repository.Get(ctx, params)
+	if err != nil {
+		return models.SubscriptionList{}, fmt.Errorf("SubscriptionService List() %w", err)
+	}
+
+	list := models.SubscriptionList{Subscriptions: subscriptions}
+	if len(subscriptions) > limit {
+		list.Subscriptions = subscriptions[:limit]
+		next := models.NewCursor(list.Subscriptions[limit-1], params.Sort)
+		list.NextCursor = &next
+	}
+	if list.Subscriptions == nil {
+		list.Subscriptions = []models.Subscription{}
+	}
+	return list, nil
+}
+
+func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID, ifVersions []int64) (err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
 	if err != nil {
+		return fmt.Errorf("SubscriptionService Delete() %w", err)
+	}
+	ctx = scope.bind(ctx)
 
+	err = s.repository.Delete(ctx, id, ifVersions)
+	if err != nil {
 		return fmt.Errorf("SubscriptionService Delete() %w", err)
 	}
+	s.metrics.SubscriptionsDeleted.Inc()
 	return err
 }
 
-func (s *SubscriptionService) Update(uuid uuid.UUID, subscription models.Subscription) error {
-	err := s.repository.Update(uuid, subscription)
+func (s *SubscriptionService) Update(ctx context.Context, id uuid.UUID, subscription models.Subscription, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Update")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
 	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Update() %w", err)
+	}
+	if err := scope.assign(subscription.UserID); err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Update() %w", err)
+	}
+	ctx = scope.bind(ctx)
 
-		return fmt.Errorf("SubscriptionService Update() %w", err)
+	res, err := s.repository.Update(ctx, id, subscription, ifVersions)
+	if err != nil {
+
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Update() %w", err)
 	}
-	return err
+	return res, err
+}
+
+func (s *SubscriptionService) Patch(ctx context.Context, id uuid.UUID, patch models.SubscriptionPatch, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Patch")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Patch() %w", err)
+	}
+	if patch.UserID != nil {
+		if err := scope.assign(*patch.UserID); err != nil {
+			return models.Subscription{}, fmt.Errorf("SubscriptionService Patch() %w", err)
+		}
+	}
+	ctx = scope.bind(ctx)
+
+	res, err := s.repository.Patch(ctx, id, patch, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Patch() %w", err)
+	}
+	return res, err
+}
+
+func (s *SubscriptionService) SetPrice(ctx context.Context, id uuid.UUID, change models.PriceChange, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.SetPrice")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService SetPrice() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	res, err := s.repository.SetPrice(ctx, id, change, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService SetPrice() %w", err)
+	}
+	return res, err
+}
+
+// Pause приостанавливает подписку; без pause.StartMonth — с текущего месяца
+func (s *SubscriptionService) Pause(ctx context.Context, id uuid.UUID, pause models.Pause, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Pause")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Pause() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	if pause.StartMonth.IsZero() {
+		pause.StartMonth = monthStart(time.Now().UTC())
+	}
+	if pause.EndMonth != nil && pause.EndMonth.Before(pause.StartMonth) {
+		return models.Subscription{}, models.NewValidationError("end_month", "end_month must not be before start_month")
+	}
+
+	res, err := s.repository.Pause(ctx, id, pause, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Pause() %w", err)
+	}
+	return res, err
+}
+
+// Resume возобновляет подписку с месяца month; нулевой month — с текущего месяца
+func (s *SubscriptionService) Resume(ctx context.Context, id uuid.UUID, month time.Time, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Resume")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Resume() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	if month.IsZero() {
+		month = monthStart(time.Now().UTC())
+	}
+
+	res, err := s.repository.Resume(ctx, id, month, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Resume() %w", err)
+	}
+	return res, err
+}
+
+// CancelPause отменяет приостановку, которая начинается с месяца startMonth и ещё не началась
+func (s *SubscriptionService) CancelPause(ctx context.Context, id uuid.UUID, startMonth time.Time, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.CancelPause")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService CancelPause() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	res, err := s.repository.CancelPause(ctx, id, startMonth, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService CancelPause() %w", err)
+	}
+	return res, err
+}
+
+func (s *SubscriptionService) Cancel(ctx context.Context, id uuid.UUID, req models.CancelRequest, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Cancel")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Cancel() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	res, err := s.repository.Cancel(ctx, id, req, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Cancel() %w", err)
+	}
+	return res, err
+}
+
+func (s *SubscriptionService) Uncancel(ctx context.Context, id uuid.UUID, ifVersions []int64) (_ models.Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Uncancel")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Uncancel() %w", err)
+	}
+	ctx = scope.bind(ctx)
+
+	res, err := s.repository.Uncancel(ctx, id, ifVersions)
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("SubscriptionService Uncancel() %w", err)
+	}
+	return res, err
 }
 
-func (s *SubscriptionService) GetCost(params models.SubscriptionParams) (int64, error) {
-	res, err := s.repository.GetCost(params)
+// CancellationReasons возвращает отчёт по причинам отмены подписок, доступных вызывающему
+func (s *SubscriptionService) CancellationReasons(ctx context.Context, params models.SubscriptionParams) (_ []models.CancellationReasonStats, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.CancellationReasons")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionService CancellationReasons() %w", err)
+	}
+	if err := scope.restrict(&params); err != nil {
+		return nil, fmt.Errorf("SubscriptionService CancellationReasons() %w", err)
+	}
+
+	res, err := s.repository.CancellationReasons(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionService CancellationReasons() %w", err)
+	}
+	return res, nil
+}
+
+func (s *SubscriptionService) GetCost(ctx context.Context, params models.SubscriptionParams) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetCost")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("SubscriptionService GetCost() %w", err)
+	}
+	if err := scope.restrict(&params); err != nil {
+		return 0, fmt.Errorf("SubscriptionService GetCost() %w", err)
+	}
+
+	res, err := s.repository.GetCost(ctx, params)
 	if err != nil {
 		return 0, fmt.Errorf("SubscriptionService GetCost() %w", err)
 	}
 	return res, err
 }
+
+// GetCostBreakdown возвращает помесячную разбивку стоимости за период.
+// Без группировки ряд содержит каждый месяц периода, включая месяцы без активных подписок.
+func (s *SubscriptionService) GetCostBreakdown(ctx context.Context, params models.SubscriptionParams) (_ []models.CostBucket, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.GetCostBreakdown")
+	defer func() { tracing.End(span, err) }()
+
+	scope, err := scopeFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionService GetCostBreakdown() %w", err)
+	}
+	if err := scope.restrict(&params); err != nil {
+		return nil, fmt.Errorf("SubscriptionService GetCostBreakdown() %w", err)
+	}
+
+	res, err := s.repository.GetCostBreakdown(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("SubscriptionService GetCostBreakdown() %w", err)
+	}
+	if len(params.GroupBy) > 0 {
+		return res, nil
+	}
+
+	byMonth := make(map[time.Time]models.CostBucket, len(res))
+	for _, bucket := range res {
+		byMonth[monthStart(bucket.Month)] = bucket
+	}
+
+	series := make([]models.CostBucket, 0, len(res))
+	for month := monthStart(params.StartDate); !month.After(params.EndDate); month = month.AddDate(0, 1, 0) {
+		bucket, ok := byMonth[month]
+		if !ok {
+			bucket = models.CostBucket{Month: month}
+		}
+		series = append(series, bucket)
+	}
+	return series, nil
+}
+
+// Stats возвращает сводку по подпискам, оплачиваемым в текущем месяце
+func (s *SubscriptionService) Stats(ctx context.Context) (_ models.SubscriptionStats, err error) {
+	ctx, span := tracer.Start(ctx, "SubscriptionService.Stats")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := s.repository.Stats(ctx, monthStart(time.Now().UTC()))
+	if err != nil {
+		return models.SubscriptionStats{}, fmt.Errorf("SubscriptionService Stats() %w", err)
+	}
+	return res, nil
+}
+
+// monthStart приводит дату к первому числу месяца в UTC
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
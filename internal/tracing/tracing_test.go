@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BountyM/effectiveMobileTestTask/internal/config"
+	"github.com/BountyM/effectiveMobileTestTask/internal/models"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantEvents int
+	}{
+		{name: "success", err: nil, wantStatus: codes.Unset, wantEvents: 0},
+		{name: "error", err: errors.New("boom"), wantStatus: codes.Error, wantEvents: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			provider, err := NewProvider(exporter, config.Trace{ServiceName: "subscription-api", SampleRatio: 1}, models.BuildInfo{Version: "v1.2.3"})
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+			_, span := provider.Tracer("test").Start(context.Background(), "operation")
+			End(span, tt.err)
+			if err := provider.ForceFlush(context.Background()); err != nil {
+				t.Fatalf("ForceFlush() error = %v", err)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("exported %d spans, want 1", len(spans))
+			}
+			got := spans[0]
+			if got.Status.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Status.Code, tt.wantStatus)
+			}
+			if tt.err != nil && got.Status.Description != tt.err.Error() {
+				t.Errorf("status description = %q, want %q", got.Status.Description, tt.err.Error())
+			}
+			if len(got.Events) != tt.wantEvents {
+				t.Errorf("events = %d, want %d", len(got.Events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestNewProviderResource(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := NewProvider(exporter, config.Trace{ServiceName: "subscription-api", SampleRatio: 1}, models.BuildInfo{Version: "v1.2.3"})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	_, span := provider.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	want := map[string]string{
+		string(semconv.ServiceNameKey):    "subscription-api",
+		string(semconv.ServiceVersionKey): "v1.2.3",
+	}
+	for _, attr := range spans[0].Resource.Attributes() {
+		if value, ok := want[string(attr.Key)]; ok {
+			if attr.Value.AsString() != value {
+				t.Errorf("resource %s = %q, want %q", attr.Key, attr.Value.AsString(), value)
+			}
+			delete(want, string(attr.Key))
+		}
+	}
+	for key := range want {
+		t.Errorf("resource has no %s", key)
+	}
+}
+
+func TestNewProviderSampling(t *testing.T) {
+	tests := []struct {
+		name  string
+		ratio float64
+		want  int
+	}{
+		{name: "all", ratio: 1, want: 1},
+		{name: "none", ratio: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			provider, err := NewProvider(exporter, config.Trace{ServiceName: "subscription-api", SampleRatio: tt.ratio}, models.BuildInfo{})
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+			_, span := provider.Tracer("test").Start(context.Background(), "operation")
+			span.End()
+			if err := provider.ForceFlush(context.Background()); err != nil {
+				t.Fatalf("ForceFlush() error = %v", err)
+			}
+
+			if got := len(exporter.GetSpans()); got != tt.want {
+				t.Errorf("exported %d spans, want %d", got, tt.want)
+			}
+		})
+	}
+}